@@ -1,14 +1,72 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"path/filepath"
 
 	"github.com/tjapit/monkey/src/repl"
 )
 
+var engine = flag.String(
+	"engine",
+	repl.EngineVM,
+	"execution engine to use: \"vm\" or \"eval\"",
+)
+
+var checked = flag.Bool(
+	"checked",
+	false,
+	"report int64 overflow as an error instead of promoting to a big integer",
+)
+
+var modulePath = flag.String(
+	"path",
+	"",
+	"list of directories searched for imported modules, separated by the OS path list separator",
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage:
+  monkey [flags]               start the interactive REPL
+  monkey [flags] run <file>    execute a Monkey source file
+  monkey [flags] run -         execute Monkey source read from stdin
+
+Piping a program into monkey without arguments executes it as well.
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
 func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	config := repl.Config{
+		Engine:            *engine,
+		CheckedArithmetic: *checked,
+		ModulePath:        filepath.SplitList(*modulePath),
+	}
+	args := flag.Args()
+
+	switch {
+	case len(args) == 0 && isPiped(os.Stdin):
+		os.Exit(runScript("<stdin>", os.Stdin, config))
+	case len(args) == 0:
+		startRepl(config)
+	case args[0] == "run" && len(args) == 2:
+		os.Exit(runFile(args[1], config))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func startRepl(config repl.Config) {
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -18,5 +76,44 @@ func main() {
 		user.Username,
 	)
 	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+
+	err = repl.Start(os.Stdin, os.Stdout, config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+// Executes the file at path, "-" reads from stdin. Returns the exit status.
+func runFile(path string, config repl.Config) int {
+	if path == "-" {
+		return runScript("<stdin>", os.Stdin, config)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer file.Close()
+
+	return runScript(path, file, config)
+}
+
+func runScript(filename string, in io.Reader, config repl.Config) int {
+	err := repl.Run(filename, in, config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// Reports whether f is a pipe or file rather than an interactive terminal.
+func isPiped(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
 }
@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
+	"math/big"
 	"strings"
 
 	"github.com/tjapit/monkey/src/token"
@@ -10,6 +12,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the node's token in the source
 }
 
 type Statement interface {
@@ -22,6 +25,13 @@ type Expression interface {
 	expressionNode()
 }
 
+// Destructuring target of a let statement.
+type Pattern interface {
+	Node
+	patternNode()
+	Names() []*Identifier // the variables the pattern binds
+}
+
 type Program struct {
 	Statements []Statement
 }
@@ -34,6 +44,13 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 	for _, s := range p.Statements {
@@ -43,18 +60,28 @@ func (p *Program) String() string {
 }
 
 type LetStatement struct {
-	Token token.Token // the token.LET token
-	Name  *Identifier
-	Value Expression
+	Token    token.Token // the token.LET token
+	Name     *Identifier
+	Pattern  Pattern // destructures Value instead of binding it to Name
+	Value    Expression
+	Exported bool // `export let`, visible to modules importing the program
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
+	if ls.Exported {
+		out.WriteString("export ")
+	}
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -64,6 +91,120 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// Returns the variables the statement binds.
+func (ls *LetStatement) Names() []*Identifier {
+	if ls.Pattern != nil {
+		return ls.Pattern.Names()
+	}
+	return []*Identifier{ls.Name}
+}
+
+// Matches anything, binding it to Name, e.g. `x`.
+type BindingPattern struct {
+	Name *Identifier
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Name.TokenLiteral() }
+func (bp *BindingPattern) Pos() token.Position  { return bp.Name.Pos() }
+func (bp *BindingPattern) String() string       { return bp.Name.String() }
+func (bp *BindingPattern) Names() []*Identifier { return []*Identifier{bp.Name} }
+
+// Matches anything without binding it: `_`.
+type WildcardPattern struct {
+	Token token.Token // the `_` token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) Pos() token.Position  { return wp.Token.Pos }
+func (wp *WildcardPattern) String() string       { return "_" }
+func (wp *WildcardPattern) Names() []*Identifier { return nil }
+
+// Matches values equal to a number, string or boolean literal. Negative
+// numbers are prefix expressions.
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) Pos() token.Position  { return lp.Value.Pos() }
+func (lp *LiteralPattern) String() string {
+	if _, ok := lp.Value.(*StringLiteral); ok {
+		return fmt.Sprintf("%q", lp.Value.String())
+	}
+	return lp.Value.String()
+}
+func (lp *LiteralPattern) Names() []*Identifier { return nil }
+
+// Matches the elements of an array in order, e.g. `[a, b, ...rest]`.
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []Pattern
+	Rest     Pattern // matches an array of the remaining elements, if set
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) Pos() token.Position  { return ap.Token.Pos }
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+func (ap *ArrayPattern) Names() []*Identifier {
+	names := []*Identifier{}
+	for _, e := range ap.Elements {
+		names = append(names, e.Names()...)
+	}
+	if ap.Rest != nil {
+		names = append(names, ap.Rest.Names()...)
+	}
+	return names
+}
+
+// Matches the values of a hash's string keys, e.g. `{"kind": "circle", r}`.
+// A bare name matches the key of the same name and binds its value.
+type HashPattern struct {
+	Token token.Token // the '{' token
+	Pairs []HashPatternPair
+}
+
+type HashPatternPair struct {
+	Key   string
+	Value Pattern
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) Pos() token.Position  { return hp.Token.Pos }
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for _, pair := range hp.Pairs {
+		if b, ok := pair.Value.(*BindingPattern); ok && b.Name.Value == pair.Key {
+			pairs = append(pairs, pair.Key)
+		} else {
+			pairs = append(pairs, fmt.Sprintf("%q: %s", pair.Key, pair.Value))
+		}
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+func (hp *HashPattern) Names() []*Identifier {
+	names := []*Identifier{}
+	for _, pair := range hp.Pairs {
+		names = append(names, pair.Value.Names()...)
+	}
+	return names
+}
+
 type Identifier struct {
 	Token token.Token // the token.IDENT token
 	Value string
@@ -71,6 +212,7 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
 func (i *Identifier) String() string       { return i.Value }
 
 type ReturnStatement struct {
@@ -80,6 +222,7 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -92,6 +235,88 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+type WhileStatement struct {
+	Token     token.Token // the token.WHILE token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while ")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// Loops over the elements of an array, string or hash. With a single name,
+// e.g. `for (x in arr)`, it's bound to each element, character or hash key.
+// With two names, e.g. `for (k, v in hash)`, they're bound to each index and
+// element, or key and value.
+type ForStatement struct {
+	Token    token.Token   // the token.FOR token
+	Names    []*Identifier // one or two loop variables
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, n := range fs.Names {
+		names = append(names, n.String())
+	}
+
+	out.WriteString("for (")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token // the token.BREAK token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) String() string       { return bs.TokenLiteral() + ";" }
+
+type ContinueStatement struct {
+	Token token.Token // the token.CONTINUE token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) String() string       { return cs.TokenLiteral() + ";" }
+
+type ThrowStatement struct {
+	Token token.Token // the token.THROW token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
 type ExpressionStatement struct {
 	Token      token.Token // the first token of the expression
 	Expression Expression
@@ -99,6 +324,7 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -113,8 +339,30 @@ type IntegerLiteral struct {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// Integer literal too large for int64.
+type BigIntLiteral struct {
+	Token token.Token // token.INT
+	Value *big.Int
+}
+
+func (bl *BigIntLiteral) expressionNode()      {}
+func (bl *BigIntLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BigIntLiteral) Pos() token.Position  { return bl.Token.Pos }
+func (bl *BigIntLiteral) String() string       { return bl.Token.Literal }
+
+type FloatLiteral struct {
+	Token token.Token // token.FLOAT
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type PrefixExpression struct {
 	Token    token.Token // The prefix token, e.g. ! or -
 	Operator string
@@ -123,6 +371,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -143,6 +392,7 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position  { return ie.Token.Pos }
 
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
@@ -163,6 +413,7 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 type IfExpression struct {
@@ -174,6 +425,7 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -197,6 +449,7 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) expressionNode()      {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -207,31 +460,190 @@ func (bs *BlockStatement) String() string {
 	return out.String()
 }
 
+// Evaluates to the Body of the first arm whose pattern matches Subject and
+// whose guard, if any, is truthy.
+type MatchExpression struct {
+	Token   token.Token // the `match` token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression // `if cond` after the pattern, optional
+	Body    Expression
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() token.Position  { return me.Token.Pos }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// Reports whether the arm matches any value: it has no guard and its pattern
+// is a wildcard or a name.
+func (ma *MatchArm) IsCatchAll() bool {
+	if ma.Guard != nil {
+		return false
+	}
+
+	switch ma.Pattern.(type) {
+	case *WildcardPattern, *BindingPattern:
+		return true
+	default:
+		return false
+	}
+}
+
+// Evaluates to the value of Block, or of Catch if Block threw an error. Catch
+// and Finally are optional, but at least one of them is present.
+type TryExpression struct {
+	Token     token.Token // the token.TRY token
+	Block     *BlockStatement
+	CatchName *Identifier // binds the caught error, nil without Catch
+	Catch     *BlockStatement
+	Finally   *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) Pos() token.Position  { return te.Token.Pos }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch (")
+		out.WriteString(te.CatchName.String())
+		out.WriteString(") ")
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
+
 type FunctionLiteral struct {
 	Token      token.Token // the `fn` token
 	Parameters []*Identifier
+	Defaults   []Expression // default values of the last len(Defaults) parameters
+	Rest       *Identifier  // bound to an array of the remaining arguments, if set
 	Body       *BlockStatement
+	Name       string // name of the let binding, if any
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
+	out.WriteString(fl.TokenLiteral())
+	if fl.Name != "" {
+		out.WriteString(fmt.Sprintf("<%s>", fl.Name))
+	}
+	out.WriteString("(")
+	out.WriteString(FormatParameters(fl.Parameters, fl.Defaults, fl.Rest))
+	out.WriteString(") ")
+	out.WriteString(fl.Body.String())
+
+	return out.String()
+}
+
+// Formats the parameter list of a function, e.g. `a, b = 10, ...rest`.
+func FormatParameters(params []*Identifier, defaults []Expression, rest *Identifier) string {
+	formatted := []string{}
+	firstDefault := len(params) - len(defaults)
+	for i, p := range params {
+		if i >= firstDefault {
+			formatted = append(formatted, p.String()+" = "+defaults[i-firstDefault].String())
+		} else {
+			formatted = append(formatted, p.String())
+		}
+	}
+	if rest != nil {
+		formatted = append(formatted, "..."+rest.String())
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// Defines a macro, e.g. `let unless = macro(cond, body) { ... }`. Its body
+// runs before the program does, with the arguments of each call as quoted
+// code, and the quoted code it returns replaces the call.
+type MacroLiteral struct {
+	Token      token.Token // the `macro` token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() token.Position  { return ml.Token.Pos }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
 	params := []string{}
-	for _, p := range fl.Parameters {
+	for _, p := range ml.Parameters {
 		params = append(params, p.String())
 	}
 
-	out.WriteString(fl.TokenLiteral())
+	out.WriteString(ml.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
-	out.WriteString(fl.Body.String())
+	out.WriteString(ml.Body.String())
 
 	return out.String()
 }
 
+// Loads the module at Path, e.g. `import("lib/math.mk")`.
+type ImportExpression struct {
+	Token token.Token // the `import` token
+	Path  string
+}
+
+func (ie *ImportExpression) expressionNode()      {}
+func (ie *ImportExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *ImportExpression) Pos() token.Position  { return ie.Token.Pos }
+func (ie *ImportExpression) String() string {
+	return ie.TokenLiteral() + "(\"" + ie.Path + "\")"
+}
+
 type CallExpression struct {
 	Token     token.Token // the `(` token
 	Function  Expression  // Identifier or FunctionLiteral
@@ -240,6 +652,7 @@ type CallExpression struct {
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Token.Pos }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -256,6 +669,18 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// Passes the elements of an array as separate arguments of a call, e.g.
+// `f(...args)`.
+type SpreadExpression struct {
+	Token token.Token // the `...` token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) Pos() token.Position  { return se.Token.Pos }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
 type StringLiteral struct {
 	Token token.Token // the '"' token
 	Value string
@@ -263,6 +688,7 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
 type ArrayLiteral struct {
@@ -272,6 +698,7 @@ type ArrayLiteral struct {
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -295,6 +722,7 @@ type IndexExpression struct {
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -307,6 +735,30 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// Assignment to an existing variable or to an element of an array or hash,
+// e.g. `x = 5`, `x += 1` or `arr[0] = x`. Evaluates to the assigned value.
+type AssignExpression struct {
+	Token    token.Token // the assignment token, e.g. =, +=, -=, etc.
+	Operator string
+	Target   Expression // *Identifier or *IndexExpression
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return ae.Token.Pos }
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type HashLiteral struct {
 	Token token.Token // the '{' token
 	Pairs map[Expression]Expression
@@ -314,6 +766,7 @@ type HashLiteral struct {
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
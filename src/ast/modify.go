@@ -0,0 +1,182 @@
+package ast
+
+// Returns the node to put in place of the given one, e.g. itself to keep it.
+type ModifierFunc func(Node) Node
+
+// Rewrites a tree by passing each of its nodes through modifier, children
+// before their parents, so modifier sees the already modified children.
+// Composite nodes are copied rather than changed in place, the original tree
+// stays intact. Names declared by let statements, loops, function parameters
+// and catch clauses aren't passed to modifier.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case nil:
+		return nil
+
+	// Statements
+	case *Program:
+		modified := *node
+		modified.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&modified)
+	case *ExpressionStatement:
+		modified := *node
+		modified.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&modified)
+	case *BlockStatement:
+		modified := *node
+		modified.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&modified)
+	case *LetStatement:
+		modified := *node
+		modified.Value = modifyExpression(node.Value, modifier)
+		return modifier(&modified)
+	case *ReturnStatement:
+		modified := *node
+		modified.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		return modifier(&modified)
+	case *ThrowStatement:
+		modified := *node
+		modified.Value = modifyExpression(node.Value, modifier)
+		return modifier(&modified)
+	case *WhileStatement:
+		modified := *node
+		modified.Condition = modifyExpression(node.Condition, modifier)
+		modified.Body = modifyBlock(node.Body, modifier)
+		return modifier(&modified)
+	case *ForStatement:
+		modified := *node
+		modified.Iterable = modifyExpression(node.Iterable, modifier)
+		modified.Body = modifyBlock(node.Body, modifier)
+		return modifier(&modified)
+
+	// Expressions
+	case *PrefixExpression:
+		modified := *node
+		modified.Right = modifyExpression(node.Right, modifier)
+		return modifier(&modified)
+	case *InfixExpression:
+		modified := *node
+		modified.Left = modifyExpression(node.Left, modifier)
+		modified.Right = modifyExpression(node.Right, modifier)
+		return modifier(&modified)
+	case *AssignExpression:
+		modified := *node
+		modified.Target = modifyExpression(node.Target, modifier)
+		modified.Value = modifyExpression(node.Value, modifier)
+		return modifier(&modified)
+	case *IndexExpression:
+		modified := *node
+		modified.Left = modifyExpression(node.Left, modifier)
+		modified.Index = modifyExpression(node.Index, modifier)
+		return modifier(&modified)
+	case *IfExpression:
+		modified := *node
+		modified.Condition = modifyExpression(node.Condition, modifier)
+		modified.Consequence = modifyBlock(node.Consequence, modifier)
+		modified.Alternative = modifyBlock(node.Alternative, modifier)
+		return modifier(&modified)
+	case *MatchExpression:
+		modified := *node
+		modified.Subject = modifyExpression(node.Subject, modifier)
+		modified.Arms = make([]*MatchArm, len(node.Arms))
+		for i, arm := range node.Arms {
+			// patterns aren't expressions, only guards and bodies are modified
+			modifiedArm := *arm
+			modifiedArm.Guard = modifyExpression(arm.Guard, modifier)
+			modifiedArm.Body = modifyExpression(arm.Body, modifier)
+			modified.Arms[i] = &modifiedArm
+		}
+		return modifier(&modified)
+	case *TryExpression:
+		modified := *node
+		modified.Block = modifyBlock(node.Block, modifier)
+		modified.Catch = modifyBlock(node.Catch, modifier)
+		modified.Finally = modifyBlock(node.Finally, modifier)
+		return modifier(&modified)
+	case *FunctionLiteral:
+		modified := *node
+		modified.Defaults = modifyExpressions(node.Defaults, modifier)
+		modified.Body = modifyBlock(node.Body, modifier)
+		return modifier(&modified)
+	case *MacroLiteral:
+		modified := *node
+		modified.Body = modifyBlock(node.Body, modifier)
+		return modifier(&modified)
+	case *CallExpression:
+		modified := *node
+		modified.Function = modifyExpression(node.Function, modifier)
+		modified.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&modified)
+	case *SpreadExpression:
+		modified := *node
+		modified.Value = modifyExpression(node.Value, modifier)
+		return modifier(&modified)
+	case *ArrayLiteral:
+		modified := *node
+		modified.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&modified)
+	case *HashLiteral:
+		modified := *node
+		modified.Pairs = make(map[Expression]Expression, len(node.Pairs))
+		for key, value := range node.Pairs {
+			newKey := modifyExpression(key, modifier)
+			modified.Pairs[newKey] = modifyExpression(value, modifier)
+		}
+		return modifier(&modified)
+	}
+
+	return modifier(node)
+}
+
+// Keeps the original statement if modifier replaced it by something else.
+func modifyStatements(statements []Statement, modifier ModifierFunc) []Statement {
+	if statements == nil {
+		return nil
+	}
+
+	modified := make([]Statement, len(statements))
+	for i, s := range statements {
+		if stmt, ok := Modify(s, modifier).(Statement); ok {
+			modified[i] = stmt
+		} else {
+			modified[i] = s
+		}
+	}
+	return modified
+}
+
+func modifyExpressions(expressions []Expression, modifier ModifierFunc) []Expression {
+	if expressions == nil {
+		return nil
+	}
+
+	modified := make([]Expression, len(expressions))
+	for i, e := range expressions {
+		modified[i] = modifyExpression(e, modifier)
+	}
+	return modified
+}
+
+// Keeps the original expression if modifier replaced it by something else.
+func modifyExpression(expression Expression, modifier ModifierFunc) Expression {
+	if expression == nil {
+		return nil
+	}
+
+	if modified, ok := Modify(expression, modifier).(Expression); ok {
+		return modified
+	}
+	return expression
+}
+
+// Keeps the original block if modifier replaced it by something else.
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+
+	if modified, ok := Modify(block, modifier).(*BlockStatement); ok {
+		return modified
+	}
+	return block
+}
@@ -0,0 +1,216 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+
+		if integer.Value != 1 {
+			return node
+		}
+
+		return two()
+	}
+
+	testCases := []struct {
+		desc     string
+		input    Node
+		expected Node
+	}{
+		{"Test 1", one(), two()},
+		{
+			"Test 2",
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		},
+		{
+			"Test 3",
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			"Test 4",
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			"Test 5",
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			"Test 6",
+			&IfExpression{
+				Condition: one(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: one()}},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: one()}},
+				},
+			},
+			&IfExpression{
+				Condition: two(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: two()}},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: two()}},
+				},
+			},
+		},
+		{
+			"Test 7",
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			"Test 8",
+			&LetStatement{Name: &Identifier{Value: "x"}, Value: one()},
+			&LetStatement{Name: &Identifier{Value: "x"}, Value: two()},
+		},
+		{
+			"Test 9",
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: one()}},
+				},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: two()}},
+				},
+			},
+		},
+		{
+			"Test 10",
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+		{
+			"Test 11",
+			&CallExpression{Function: one(), Arguments: []Expression{one()}},
+			&CallExpression{Function: two(), Arguments: []Expression{two()}},
+		},
+		{
+			"Test 12",
+			&WhileStatement{
+				Condition: one(),
+				Body:      &BlockStatement{Statements: []Statement{&ThrowStatement{Value: one()}}},
+			},
+			&WhileStatement{
+				Condition: two(),
+				Body:      &BlockStatement{Statements: []Statement{&ThrowStatement{Value: two()}}},
+			},
+		},
+		{
+			"Test 13",
+			&ForStatement{Iterable: one(), Body: &BlockStatement{}},
+			&ForStatement{Iterable: two(), Body: &BlockStatement{}},
+		},
+		{
+			"Test 14",
+			&AssignExpression{Target: one(), Operator: "=", Value: one()},
+			&AssignExpression{Target: two(), Operator: "=", Value: two()},
+		},
+		{
+			"Test 15",
+			&TryExpression{
+				Block: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: one()}},
+				},
+				Finally: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: one()}},
+				},
+			},
+			&TryExpression{
+				Block: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: two()}},
+				},
+				Finally: &BlockStatement{
+					Statements: []Statement{&ExpressionStatement{Expression: two()}},
+				},
+			},
+		},
+		{
+			"Test 16",
+			&MatchExpression{
+				Subject: one(),
+				Arms: []*MatchArm{
+					{Pattern: &LiteralPattern{Value: one()}, Guard: one(), Body: one()},
+				},
+			},
+			&MatchExpression{
+				Subject: two(),
+				Arms: []*MatchArm{
+					{Pattern: &LiteralPattern{Value: one()}, Guard: two(), Body: two()},
+				},
+			},
+		},
+		{
+			"Test 17",
+			&FunctionLiteral{
+				Parameters: []*Identifier{{Value: "a"}},
+				Defaults:   []Expression{one()},
+				Body:       &BlockStatement{},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{{Value: "a"}},
+				Defaults:   []Expression{two()},
+				Body:       &BlockStatement{},
+			},
+		},
+		{
+			"Test 18",
+			&CallExpression{Function: one(), Arguments: []Expression{&SpreadExpression{Value: one()}}},
+			&CallExpression{Function: two(), Arguments: []Expression{&SpreadExpression{Value: two()}}},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			modified := Modify(tC.input, turnOneIntoTwo)
+
+			if !reflect.DeepEqual(modified, tC.expected) {
+				t.Errorf("not equal. got=%#v, want=%#v", modified, tC.expected)
+			}
+		})
+	}
+
+	infix := &InfixExpression{Left: one(), Operator: "+", Right: one()}
+	Modify(infix, turnOneIntoTwo)
+	if infix.Left.(*IntegerLiteral).Value != 1 {
+		t.Errorf("Modify changed its input: %#v", infix)
+	}
+
+	hashLiteral := &HashLiteral{
+		Pairs: map[Expression]Expression{
+			one(): one(),
+			one(): one(),
+		},
+	}
+
+	modified := Modify(hashLiteral, turnOneIntoTwo).(*HashLiteral)
+
+	for key, val := range modified.Pairs {
+		key, _ := key.(*IntegerLiteral)
+		if key.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, key.Value)
+		}
+		val, _ := val.(*IntegerLiteral)
+		if val.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, val.Value)
+		}
+	}
+}
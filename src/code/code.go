@@ -18,6 +18,7 @@ const (
 	OpSub
 	OpMul
 	OpDiv
+	OpMod
 
 	OpTrue
 	OpFalse
@@ -25,12 +26,62 @@ const (
 	OpEqual
 	OpNotEqual
 	OpGreaterThan
+	OpGreaterThanOrEqual
+	OpLessThan
+	OpLessThanOrEqual
 
 	OpMinus
 	OpBang
 
 	OpJumpNotTruthy
 	OpJump
+
+	OpGetGlobal
+	OpSetGlobal
+
+	OpNull
+
+	OpArray
+	OpHash
+	OpIndex
+	OpSetIndex
+	OpDupPair
+
+	OpCall
+	OpReturnValue
+	OpReturn
+
+	OpGetLocal
+	OpSetLocal
+
+	OpClosure
+	OpGetFree
+	OpSetFree
+	OpCurrentClosure
+	OpCaptureLocal
+	OpCaptureFree
+
+	OpGetBuiltin
+
+	OpIterInit
+	OpIterNext
+
+	OpTry
+	OpEndTry
+	OpThrow
+
+	OpQuote
+
+	OpImport
+
+	OpDestructureArray
+	OpDestructureHash
+
+	OpMatch
+	OpNoMatch
+
+	OpCallSpread
+	OpJumpArgPassed
 )
 
 type Definition struct {
@@ -46,19 +97,81 @@ var definitions = map[Opcode]*Definition{
 	OpSub: {"OpSub", []int{}},
 	OpMul: {"OpMul", []int{}},
 	OpDiv: {"OpDiv", []int{}},
+	OpMod: {"OpMod", []int{}},
 
 	OpTrue:  {"OpTrue", []int{}},
 	OpFalse: {"OpFalse", []int{}},
 
-	OpEqual:       {"OpEqual", []int{}},
-	OpNotEqual:    {"OpNotEqual", []int{}},
-	OpGreaterThan: {"OpGreaterThan", []int{}},
+	OpEqual:              {"OpEqual", []int{}},
+	OpNotEqual:           {"OpNotEqual", []int{}},
+	OpGreaterThan:        {"OpGreaterThan", []int{}},
+	OpGreaterThanOrEqual: {"OpGreaterThanOrEqual", []int{}},
+	OpLessThan:           {"OpLessThan", []int{}},
+	OpLessThanOrEqual:    {"OpLessThanOrEqual", []int{}},
 
 	OpMinus: {"OpMinus", []int{}},
 	OpBang:  {"OpBang", []int{}},
 
 	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
 	OpJump:          {"OpJump", []int{2}},
+
+	OpGetGlobal: {"OpGetGlobal", []int{2}},
+	OpSetGlobal: {"OpSetGlobal", []int{2}},
+
+	OpNull: {"OpNull", []int{}},
+
+	OpArray: {"OpArray", []int{2}}, // operand: number of elements
+	OpHash:  {"OpHash", []int{2}},  // operand: number of keys + values
+	OpIndex: {"OpIndex", []int{}},
+
+	OpSetIndex: {"OpSetIndex", []int{}}, // stack: container, index, value
+	OpDupPair:  {"OpDupPair", []int{}},  // duplicates the top two stack elements
+
+	OpCall:        {"OpCall", []int{1}}, // operand: number of arguments
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+
+	OpGetLocal: {"OpGetLocal", []int{1}},
+	OpSetLocal: {"OpSetLocal", []int{1}},
+
+	OpClosure:        {"OpClosure", []int{2, 1}}, // operands: constant index, number of free variables
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpSetFree:        {"OpSetFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+
+	// push the cell of a variable for OpClosure to capture
+	OpCaptureLocal: {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:  {"OpCaptureFree", []int{1}},
+
+	OpGetBuiltin: {"OpGetBuiltin", []int{1}}, // operand: index into object.Builtins
+
+	OpIterInit: {"OpIterInit", []int{}},
+	OpIterNext: {"OpIterNext", []int{1}}, // operand: number of loop variables
+
+	OpTry:    {"OpTry", []int{2}}, // operand: address of the error handler
+	OpEndTry: {"OpEndTry", []int{}},
+	OpThrow:  {"OpThrow", []int{}},
+
+	// operands: constant index of the quoted code, number of unquoted values
+	OpQuote: {"OpQuote", []int{2, 1}},
+
+	// operand: constant index of the imported path
+	OpImport: {"OpImport", []int{2}},
+
+	// operands: number of elements, 1 if the remaining ones are bound too
+	OpDestructureArray: {"OpDestructureArray", []int{2, 1}},
+	// operand: number of keys, pushed after the hash
+	OpDestructureHash: {"OpDestructureHash", []int{2}},
+
+	// operand: constant index of the pattern. Pushes the bound values and
+	// true if the subject matches, else false.
+	OpMatch:   {"OpMatch", []int{2}},
+	OpNoMatch: {"OpNoMatch", []int{}},
+
+	// operand: number of arrays whose elements are the arguments
+	OpCallSpread: {"OpCallSpread", []int{1}},
+	// operands: parameter index, address to jump to if its argument was passed
+	OpJumpArgPassed: {"OpJumpArgPassed", []int{1, 2}},
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -91,6 +204,8 @@ func Make(op Opcode, operands ...int) []byte {
 		switch width {
 		case 2:
 			binary.BigEndian.PutUint16(instruction[offset:], uint16(operand))
+		case 1:
+			instruction[offset] = byte(operand)
 		}
 		offset += width
 	}
@@ -135,6 +250,8 @@ func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 		return fmt.Sprintf("%s", def.Name)
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
 
 	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
@@ -149,6 +266,8 @@ func ReadOperands(def *Definition, operandsBytes []byte) ([]int, int) {
 		switch width {
 		case 2:
 			operands[i] = int(ReadUint16(operandsBytes[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(operandsBytes[offset:]))
 		}
 
 		offset += width
@@ -159,3 +278,7 @@ func ReadOperands(def *Definition, operandsBytes []byte) ([]int, int) {
 func ReadUint16(operand Instructions) uint16 {
 	return binary.BigEndian.Uint16(operand)
 }
+
+func ReadUint8(operand Instructions) uint8 {
+	return uint8(operand[0])
+}
@@ -29,6 +29,44 @@ func TestMake(t *testing.T) {
 		{"Test 11", OpGreaterThan, []int{}, []byte{byte(OpGreaterThan)}},
 		{"Test 12", OpMinus, []int{}, []byte{byte(OpMinus)}},
 		{"Test 13", OpBang, []int{}, []byte{byte(OpBang)}},
+		{"Test 14", OpGetGlobal, []int{65534}, []byte{byte(OpGetGlobal), 255, 254}},
+		{"Test 15", OpSetGlobal, []int{65534}, []byte{byte(OpSetGlobal), 255, 254}},
+		{"Test 16", OpNull, []int{}, []byte{byte(OpNull)}},
+		{"Test 17", OpArray, []int{65534}, []byte{byte(OpArray), 255, 254}},
+		{"Test 18", OpHash, []int{65534}, []byte{byte(OpHash), 255, 254}},
+		{"Test 19", OpIndex, []int{}, []byte{byte(OpIndex)}},
+		{"Test 20", OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{"Test 21", OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+		{"Test 22", OpGetBuiltin, []int{255}, []byte{byte(OpGetBuiltin), 255}},
+		{"Test 23", OpMod, []int{}, []byte{byte(OpMod)}},
+		{"Test 24", OpGreaterThanOrEqual, []int{}, []byte{byte(OpGreaterThanOrEqual)}},
+		{"Test 25", OpLessThan, []int{}, []byte{byte(OpLessThan)}},
+		{"Test 26", OpLessThanOrEqual, []int{}, []byte{byte(OpLessThanOrEqual)}},
+		{"Test 27", OpSetIndex, []int{}, []byte{byte(OpSetIndex)}},
+		{"Test 28", OpDupPair, []int{}, []byte{byte(OpDupPair)}},
+		{"Test 29", OpIterInit, []int{}, []byte{byte(OpIterInit)}},
+		{"Test 30", OpIterNext, []int{2}, []byte{byte(OpIterNext), 2}},
+		{"Test 31", OpTry, []int{65534}, []byte{byte(OpTry), 255, 254}},
+		{"Test 32", OpEndTry, []int{}, []byte{byte(OpEndTry)}},
+		{"Test 33", OpThrow, []int{}, []byte{byte(OpThrow)}},
+		{"Test 34", OpQuote, []int{65534, 255}, []byte{byte(OpQuote), 255, 254, 255}},
+		{"Test 35", OpImport, []int{65534}, []byte{byte(OpImport), 255, 254}},
+		{
+			"Test 36",
+			OpDestructureArray,
+			[]int{65534, 1},
+			[]byte{byte(OpDestructureArray), 255, 254, 1},
+		},
+		{"Test 37", OpDestructureHash, []int{65534}, []byte{byte(OpDestructureHash), 255, 254}},
+		{"Test 38", OpMatch, []int{65534}, []byte{byte(OpMatch), 255, 254}},
+		{"Test 39", OpNoMatch, []int{}, []byte{byte(OpNoMatch)}},
+		{"Test 40", OpCallSpread, []int{255}, []byte{byte(OpCallSpread), 255}},
+		{
+			"Test 41",
+			OpJumpArgPassed,
+			[]int{255, 65534},
+			[]byte{byte(OpJumpArgPassed), 255, 255, 254},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -72,6 +110,8 @@ func TestInstructionsString(t *testing.T) {
 		Make(OpGreaterThan),
 		Make(OpMinus),
 		Make(OpBang),
+		Make(OpGetLocal, 1),
+		Make(OpClosure, 65535, 255),
 	}
 
 	expected := `0000 OpAdd
@@ -88,6 +128,8 @@ func TestInstructionsString(t *testing.T) {
 0015 OpGreaterThan
 0016 OpMinus
 0017 OpBang
+0018 OpGetLocal 1
+0020 OpClosure 65535 255
 `
 
 	concatted := Instructions{}
@@ -112,6 +154,8 @@ func TestReadOperands(t *testing.T) {
 		bytesRead int
 	}{
 		{"Test 1", OpConstant, []int{65535}, 2},
+		{"Test 2", OpGetLocal, []int{255}, 1},
+		{"Test 3", OpClosure, []int{65535, 255}, 3},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
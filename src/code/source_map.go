@@ -0,0 +1,43 @@
+package code
+
+import (
+	"sort"
+
+	"github.com/tjapit/monkey/src/token"
+)
+
+// Source position of the instructions starting at Offset.
+type SourcePosition struct {
+	Offset int
+	Pos    token.Position
+}
+
+// Maps instruction offsets back to source positions, e.g. to attribute
+// runtime errors. Entries are sorted by Offset and only recorded when the
+// position changes.
+type SourceMap []SourcePosition
+
+// Records that the instruction at offset was compiled from pos.
+func (m SourceMap) Add(offset int, pos token.Position) SourceMap {
+	if !pos.IsValid() || (len(m) > 0 && m[len(m)-1].Pos == pos) {
+		return m
+	}
+	return append(m, SourcePosition{Offset: offset, Pos: pos})
+}
+
+// Drops the entries of instructions at or after offset, e.g. when they're
+// removed again.
+func (m SourceMap) Truncate(offset int) SourceMap {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset >= offset })
+	return m[:i]
+}
+
+// Returns the position of the instruction containing offset, or the zero
+// Position if unknown.
+func (m SourceMap) Lookup(offset int) token.Position {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset > offset })
+	if i == 0 {
+		return token.Position{}
+	}
+	return m[i-1].Pos
+}
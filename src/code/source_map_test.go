@@ -0,0 +1,49 @@
+package code
+
+import (
+	"testing"
+
+	"github.com/tjapit/monkey/src/token"
+)
+
+func TestSourceMap(t *testing.T) {
+	var m SourceMap
+	m = m.Add(0, token.Position{Line: 1, Column: 1})
+	m = m.Add(3, token.Position{Line: 1, Column: 1}) // unchanged position
+	m = m.Add(4, token.Position{})                   // unknown position
+	m = m.Add(6, token.Position{Line: 2, Column: 5})
+	m = m.Add(9, token.Position{Line: 3, Column: 2})
+
+	if len(m) != 3 {
+		t.Fatalf("wrong number of entries. want=3, got =%d", len(m))
+	}
+
+	testCases := []struct {
+		desc     string
+		offset   int
+		expected string
+	}{
+		{"Test 1", 0, "1:1"},
+		{"Test 2", 5, "1:1"},
+		{"Test 3", 6, "2:5"},
+		{"Test 4", 8, "2:5"},
+		{"Test 5", 42, "3:2"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			pos := m.Lookup(tC.offset)
+			if pos.String() != tC.expected {
+				t.Errorf("wrong position. want=%s, got =%s", tC.expected, pos)
+			}
+		})
+	}
+
+	m = m.Truncate(9)
+	if pos := m.Lookup(42); pos.String() != "2:5" {
+		t.Errorf("wrong position after truncate. want=2:5, got =%s", pos)
+	}
+
+	if pos := (SourceMap{}).Lookup(0); pos.IsValid() {
+		t.Errorf("empty source map has position %s", pos)
+	}
+}
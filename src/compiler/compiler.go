@@ -2,10 +2,12 @@ package compiler
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/tjapit/monkey/src/ast"
 	"github.com/tjapit/monkey/src/code"
 	"github.com/tjapit/monkey/src/object"
+	"github.com/tjapit/monkey/src/token"
 )
 
 type EmittedInstruction struct {
@@ -13,29 +15,83 @@ type EmittedInstruction struct {
 	Position int
 }
 
-type Compiler struct {
-	instructions code.Instructions
-	constants    []object.Object
-
+// Instructions of a single function body being compiled.
+type CompilationScope struct {
+	instructions        code.Instructions
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
+
+	sourceMap code.SourceMap
+
+	loops []*Loop // enclosing loops, innermost last
+
+	// finally blocks of the enclosing try regions, innermost last. nil for
+	// regions without one.
+	tries []*ast.BlockStatement
+}
+
+// Jump targets of a loop being compiled.
+type Loop struct {
+	continueTarget int   // position `continue` jumps back to
+	breakJumps     []int // positions of `break` jumps to patch with the exit
+	tryDepth       int   // number of try regions enclosing the loop
+}
+
+type Compiler struct {
+	constants []object.Object
+
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	pos token.Position // position of the node being compiled
 }
 
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
+	GlobalNames  []string // names of the global variables by index
+	SourceMap    code.SourceMap
 }
 
 func New() *Compiler {
-	return &Compiler{
+	mainScope := CompilationScope{
 		instructions:        code.Instructions{},
-		constants:           []object.Object{},
 		lastInstruction:     EmittedInstruction{},
 		previousInstruction: EmittedInstruction{},
 	}
+
+	symbolTable := NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+// Creates a Compiler that keeps defining into an existing symbol table and
+// constant pool, e.g. to carry definitions across REPL lines.
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
 }
 
 func (c *Compiler) Compile(node ast.Node) error {
+	// attribute emitted instructions to the innermost node
+	if pos := node.Pos(); pos.IsValid() {
+		outerPos := c.pos
+		c.pos = pos
+		defer func() { c.pos = outerPos }()
+	}
+
 	switch node := node.(type) {
 	case *ast.Program:
 		for _, s := range node.Statements {
@@ -72,24 +128,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case "!":
 			c.emit(code.OpBang)
 		default:
-			return fmt.Errorf("unkown operator: %s", node.Operator)
+			return fmt.Errorf("%s: unknown operator: %s", node.Pos(), node.Operator)
 		}
 
 	case *ast.InfixExpression:
-		if node.Operator == "<" {
-			err := c.Compile(node.Right)
-			if err != nil {
-				return err
-			}
-
-			err = c.Compile(node.Left)
-			if err != nil {
-				return err
-			}
-
-			c.emit(code.OpGreaterThan)
-			return nil
-		} // reorder for OpLessThan "<"
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
+		}
 
 		err := c.Compile(node.Left)
 		if err != nil {
@@ -110,16 +155,27 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
 		case ">":
 			c.emit(code.OpGreaterThan)
+		case ">=":
+			c.emit(code.OpGreaterThanOrEqual)
+		case "<":
+			c.emit(code.OpLessThan)
+		case "<=":
+			c.emit(code.OpLessThanOrEqual)
 		case "==":
 			c.emit(code.OpEqual)
 		case "!=":
 			c.emit(code.OpNotEqual)
 		default:
-			return fmt.Errorf("unkown operator: %s", node.Operator)
+			return fmt.Errorf("%s: unknown operator: %s", node.Pos(), node.Operator)
 		}
 
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+
 	case *ast.IfExpression:
 		err := c.Compile(node.Condition)
 		if err != nil {
@@ -132,50 +188,851 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 
-		if c.lastInstructionIsPop() {
+		if c.lastInstructionIs(code.OpPop) {
 			c.removeLastPop() // remove extra OpPop from compiling Consequence
 		}
 
-		posAfterConsequence := len(c.instructions) // without Alternative
-
-		if node.Alternative != nil {
-			posJump := c.emit(code.OpJump, 9999) // Emit `OpJump` with bogus offset
+		posJump := c.emit(code.OpJump, 9999) // Emit `OpJump` with bogus offset
 
-			posAfterConsequence = len(c.instructions) // with Alternative
+		posAfterConsequence := len(c.currentInstructions())
+		c.changeOperand(posJumpNotTruthy, posAfterConsequence)
 
+		if node.Alternative == nil {
+			c.emit(code.OpNull) // if-expressions without Alternative produce null
+		} else {
 			err := c.Compile(node.Alternative)
 			if err != nil {
 				return err
 			}
 
-			if c.lastInstructionIsPop() {
+			if c.lastInstructionIs(code.OpPop) {
 				c.removeLastPop()
 			}
+		}
 
-			posAfterAlternative := len(c.instructions)
-			c.changeOperand(posJump, posAfterAlternative)
+		posAfterAlternative := len(c.currentInstructions())
+		c.changeOperand(posJump, posAfterAlternative)
+
+	case *ast.MatchExpression:
+		return c.compileMatchExpression(node)
+
+	case *ast.LetStatement:
+		// a function assigning to its own name assigns to this variable, so
+		// it has to exist while the function is compiled
+		var symbol Symbol
+		fn, ok := node.Value.(*ast.FunctionLiteral)
+		if ok && fn.Name != "" {
+			symbol = c.symbolTable.Define(node.Name.Value)
 		}
 
-		c.changeOperand(posJumpNotTruthy, posAfterConsequence)
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		if node.Pattern != nil {
+			c.compileDestructuring(node.Pattern)
+			return nil
+		}
+
+		if symbol.Name == "" {
+			symbol = c.symbolTable.Define(node.Name.Value)
+		}
+		c.storeSymbol(symbol)
+
+	case *ast.WhileStatement:
+		loopStart := len(c.currentInstructions())
+
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+
+		posJumpNotTruthy := c.emit(code.OpJumpNotTruthy, 9999)
+
+		err = c.compileLoopBody(node.Body, loopStart)
+		if err != nil {
+			return err
+		}
+
+		c.changeOperand(posJumpNotTruthy, len(c.currentInstructions()))
+
+	case *ast.ForStatement:
+		err := c.Compile(node.Iterable)
+		if err != nil {
+			return err
+		}
+
+		// the iterator lives in a hidden variable, `$` can't start an identifier
+		c.emit(code.OpIterInit)
+		iterator := c.symbolTable.Define("$iterator")
+		c.storeSymbol(iterator)
+
+		loopStart := len(c.currentInstructions())
+		c.loadSymbol(iterator)
+		c.emit(code.OpIterNext, len(node.Names))
+		posJumpNotTruthy := c.emit(code.OpJumpNotTruthy, 9999)
+
+		// the last loop variable's value is on top of the stack
+		for i := len(node.Names) - 1; i >= 0; i-- {
+			symbol := c.symbolTable.Define(node.Names[i].Value)
+			c.storeSymbol(symbol)
+		}
+
+		err = c.compileLoopBody(node.Body, loopStart)
+		if err != nil {
+			return err
+		}
+
+		c.changeOperand(posJumpNotTruthy, len(c.currentInstructions()))
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: break outside of loop", node.Pos())
+		}
+
+		err := c.exitTries(loop.tryDepth)
+		if err != nil {
+			return err
+		}
+
+		posJump := c.emit(code.OpJump, 9999)
+		loop.breakJumps = append(loop.breakJumps, posJump)
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: continue outside of loop", node.Pos())
+		}
+
+		err := c.exitTries(loop.tryDepth)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpJump, loop.continueTarget)
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return fmt.Errorf("%s: undefined variable %s", node.Pos(), node.Value)
+		}
+
+		c.loadSymbol(symbol)
+
+	case *ast.FunctionLiteral:
+		c.enterScope()
+
+		if node.Name != "" {
+			c.symbolTable.DefineFunctionName(node.Name)
+		}
+
+		params := []Symbol{}
+		for _, p := range node.Parameters {
+			params = append(params, c.symbolTable.Define(p.Value))
+		}
+		if node.Rest != nil {
+			c.symbolTable.Define(node.Rest.Value)
+		}
+
+		err := c.compileDefaults(params, node.Defaults)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+
+		// implicit return of the last expression
+		if c.lastInstructionIs(code.OpPop) {
+			c.replaceLastPopWithReturn()
+		}
+		// empty body or body ending with a statement
+		if !c.lastInstructionIs(code.OpReturnValue) {
+			c.emit(code.OpReturn)
+		}
+
+		freeSymbols := c.symbolTable.FreeSymbols
+		numLocals := c.symbolTable.numDefinitions
+		localNames := c.symbolTable.DefinedNames()
+		sourceMap := c.scopes[c.scopeIndex].sourceMap
+		instructions := c.leaveScope()
+
+		// push captured variables for OpClosure to pick up
+		freeNames := []string{}
+		for _, s := range freeSymbols {
+			c.captureSymbol(s)
+			freeNames = append(freeNames, s.Name)
+		}
+
+		compiledFn := &object.CompiledFunction{
+			Instructions:  instructions,
+			NumLocals:     numLocals,
+			LocalNames:    localNames,
+			FreeNames:     freeNames,
+			NumParameters: len(node.Parameters),
+			NumOptional:   len(node.Defaults),
+			Variadic:      node.Rest != nil,
+			SourceMap:     sourceMap,
+		}
+
+		fnIndex := c.addConstant(compiledFn)
+		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+
+	case *ast.ReturnStatement:
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+
+		err = c.exitTries(0)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpReturnValue)
+
+	case *ast.ThrowStatement:
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpThrow)
+
+	case *ast.TryExpression:
+		return c.compileTryExpression(node)
+
+	case *ast.ImportExpression:
+		path := &object.String{Value: node.Path}
+		c.emit(code.OpImport, c.addConstant(path))
+
+	case *ast.MacroLiteral:
+		return fmt.Errorf(
+			"%s: macros must be defined by top-level let statements",
+			node.Pos(),
+		)
+
+	case *ast.SpreadExpression:
+		// call arguments are compiled by compileSpreadArguments, spreads reach
+		// here only when macros splice them elsewhere
+		return fmt.Errorf("%s: spread not allowed here", node.Pos())
+
+	case *ast.CallExpression:
+		if object.IsQuoteCall(node) {
+			return c.compileQuote(node.Arguments[0])
+		}
+
+		err := c.Compile(node.Function)
+		if err != nil {
+			return err
+		}
+
+		if hasSpread(node.Arguments) {
+			return c.compileSpreadArguments(node.Arguments)
+		}
+
+		for _, a := range node.Arguments {
+			err := c.Compile(a)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpCall, len(node.Arguments))
 
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.BigIntLiteral:
+		integer := &object.BigInt{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
 		} else {
 			c.emit(code.OpFalse)
 		}
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		keys := []ast.Expression{}
+		for k := range node.Pairs {
+			keys = append(keys, k)
+		}
+
+		// sort keys for a deterministic instruction order, Go maps don't
+		// guarantee iteration order
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+
+		for _, k := range keys {
+			err := c.Compile(k)
+			if err != nil {
+				return err
+			}
+			err = c.Compile(node.Pairs[k])
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	case *ast.IndexExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Index)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpIndex)
+	}
+
+	return nil
+}
+
+// Compiles `&&` and `||` so the right operand is only evaluated when the left
+// one doesn't decide the result. Both produce a Boolean:
+//
+//	&&: <left>; OpJumpNotTruthy false; <right>; OpJumpNotTruthy false; true
+//	||: <left>; OpJumpNotTruthy right; OpJump true; right: <right>;
+//	    OpJumpNotTruthy false; true
+//
+// where the shared tail is `true: OpTrue; OpJump end; false: OpFalse; end:`.
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+
+	posJumpNotTruthyLeft := c.emit(code.OpJumpNotTruthy, 9999)
+
+	posJumpToTrue := -1
+	if node.Operator == "||" {
+		posJumpToTrue = c.emit(code.OpJump, 9999)
+		c.changeOperand(posJumpNotTruthyLeft, len(c.currentInstructions()))
+	}
+
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+
+	posJumpNotTruthyRight := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if posJumpToTrue != -1 {
+		c.changeOperand(posJumpToTrue, len(c.currentInstructions()))
+	}
+	c.emit(code.OpTrue)
+	posJumpToEnd := c.emit(code.OpJump, 9999)
+
+	posFalse := len(c.currentInstructions())
+	if node.Operator == "&&" {
+		c.changeOperand(posJumpNotTruthyLeft, posFalse)
+	}
+	c.changeOperand(posJumpNotTruthyRight, posFalse)
+	c.emit(code.OpFalse)
+
+	c.changeOperand(posJumpToEnd, len(c.currentInstructions()))
+
+	return nil
+}
+
+// Compiles an assignment so that it leaves the assigned value on the stack:
+//
+//	x = <value>:      <value>; OpSet x; OpGet x
+//	x += <value>:     OpGet x; <value>; OpAdd; OpSet x; OpGet x
+//	a[i] = <value>:   <a>; <i>; <value>; OpSetIndex
+//	a[i] += <value>:  <a>; <i>; OpDupPair; OpIndex; <value>; OpAdd; OpSetIndex
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(target.Value)
+		if !ok {
+			return fmt.Errorf(
+				"%s: cannot assign to undeclared variable %s",
+				node.Pos(),
+				target.Value,
+			)
+		}
+
+		switch symbol.Scope {
+		case FunctionScope:
+			symbol, ok = c.symbolTable.ResolveFunctionBinding(target.Value)
+			if !ok {
+				return fmt.Errorf(
+					"%s: cannot assign to undeclared variable %s",
+					node.Pos(),
+					target.Value,
+				)
+			}
+		case BuiltinScope:
+			return fmt.Errorf(
+				"%s: cannot assign to builtin %s",
+				node.Pos(),
+				target.Value,
+			)
+		}
+
+		if node.Operator != "=" {
+			c.loadSymbol(symbol)
+		}
+
+		err := c.compileAssignedValue(node)
+		if err != nil {
+			return err
+		}
+
+		c.storeSymbol(symbol)
+		c.loadSymbol(symbol)
+
+	case *ast.IndexExpression:
+		err := c.Compile(target.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(target.Index)
+		if err != nil {
+			return err
+		}
+
+		if node.Operator != "=" {
+			c.emit(code.OpDupPair)
+			c.emit(code.OpIndex)
+		}
+
+		err = c.compileAssignedValue(node)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpSetIndex)
+
+	default:
+		return fmt.Errorf("%s: invalid assignment target: %s", node.Pos(), node.Target)
+	}
+
+	return nil
+}
+
+// Compiles the right-hand side of an assignment. For compound operators like
+// `+=` the current value of the target must already be on the stack.
+func (c *Compiler) compileAssignedValue(node *ast.AssignExpression) error {
+	err := c.Compile(node.Value)
+	if err != nil {
+		return err
+	}
+
+	switch node.Operator {
+	case "=":
+	case "+=":
+		c.emit(code.OpAdd)
+	case "-=":
+		c.emit(code.OpSub)
+	case "*=":
+		c.emit(code.OpMul)
+	case "/=":
+		c.emit(code.OpDiv)
+	case "%=":
+		c.emit(code.OpMod)
+	default:
+		return fmt.Errorf("%s: unknown operator: %s", node.Pos(), node.Operator)
+	}
+
+	return nil
+}
+
+// Compiles the body of a loop followed by a jump back to loopStart. Breaks
+// inside the body are patched to jump past the loop.
+func (c *Compiler) compileLoopBody(
+	body *ast.BlockStatement,
+	loopStart int,
+) error {
+	scope := &c.scopes[c.scopeIndex]
+	loop := &Loop{continueTarget: loopStart, tryDepth: len(scope.tries)}
+
+	scope.loops = append(scope.loops, loop)
+
+	err := c.Compile(body)
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpJump, loopStart)
+
+	scope = &c.scopes[c.scopeIndex]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	loopExit := len(c.currentInstructions())
+	for _, pos := range loop.breakJumps {
+		c.changeOperand(pos, loopExit)
+	}
+
+	return nil
+}
+
+// Returns the innermost loop of the current function, or nil outside loops.
+func (c *Compiler) currentLoop() *Loop {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
+
+// Compiles a try expression. The instructions between OpTry and OpEndTry are
+// protected: an error thrown there unwinds to the handler address of OpTry
+// with the error on the stack. Finally blocks are compiled once for leaving
+// normally and once more for rethrowing an uncaught error:
+//
+//	OpTry catch; <block>; OpEndTry; OpJump finally
+//	catch:   OpSet e; OpTry rethrow; <catch>; OpEndTry
+//	finally: <finally>; OpJump end
+//	rethrow: OpSet $error; <finally>; OpGet $error; OpThrow
+//	end:
+func (c *Compiler) compileTryExpression(node *ast.TryExpression) error {
+	posHandler := c.emit(code.OpTry, 9999)
+
+	err := c.compileTryRegion(node.Block, node.Finally)
+	if err != nil {
+		return err
+	}
+
+	if node.Catch != nil {
+		posJumpToFinally := c.emit(code.OpJump, 9999)
+		c.changeOperand(posHandler, len(c.currentInstructions()))
+
+		symbol := c.symbolTable.Define(node.CatchName.Value)
+		c.storeSymbol(symbol)
+
+		if node.Finally != nil {
+			posHandler = c.emit(code.OpTry, 9999)
+			err = c.compileTryRegion(node.Catch, node.Finally)
+		} else {
+			err = c.compileBlockValue(node.Catch)
+		}
+		if err != nil {
+			return err
+		}
+
+		c.changeOperand(posJumpToFinally, len(c.currentInstructions()))
+	}
+
+	if node.Finally != nil {
+		err := c.Compile(node.Finally)
+		if err != nil {
+			return err
+		}
+
+		posJumpToEnd := c.emit(code.OpJump, 9999)
+		c.changeOperand(posHandler, len(c.currentInstructions()))
+
+		// the hidden variable keeps the error while the finally block runs
+		symbol := c.symbolTable.Define("$error")
+		c.storeSymbol(symbol)
+
+		err = c.Compile(node.Finally)
+		if err != nil {
+			return err
+		}
+
+		c.loadSymbol(symbol)
+		c.emit(code.OpThrow)
+
+		c.changeOperand(posJumpToEnd, len(c.currentInstructions()))
+	}
+
+	return nil
+}
+
+// Compiles a block protected by the preceding OpTry and closes the region
+// with OpEndTry.
+func (c *Compiler) compileTryRegion(
+	block *ast.BlockStatement,
+	finally *ast.BlockStatement,
+) error {
+	c.scopes[c.scopeIndex].tries = append(c.scopes[c.scopeIndex].tries, finally)
+
+	err := c.compileBlockValue(block)
+	if err != nil {
+		return err
+	}
+
+	tries := c.scopes[c.scopeIndex].tries
+	c.scopes[c.scopeIndex].tries = tries[:len(tries)-1]
+
+	c.emit(code.OpEndTry)
+	return nil
+}
+
+// Compiles a block so it leaves its value on the stack, null if it doesn't end
+// with an expression.
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	err := c.Compile(block)
+	if err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+// Leaves the try regions entered after the first depth ones before a return,
+// break or continue jumps out of them. Their finally blocks run on the way.
+func (c *Compiler) exitTries(depth int) error {
+	tries := c.scopes[c.scopeIndex].tries
+	defer func() { c.scopes[c.scopeIndex].tries = tries }()
+
+	for i := len(tries) - 1; i >= depth; i-- {
+		c.emit(code.OpEndTry)
+
+		if tries[i] == nil {
+			continue
+		}
+
+		// a finally block doesn't run inside its own try region
+		c.scopes[c.scopeIndex].tries = tries[:i]
+		err := c.Compile(tries[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Compiles the default values of the last len(defaults) parameters at the
+// start of a function. Each is only evaluated if its argument is missing, and
+// may refer to the parameters before it.
+func (c *Compiler) compileDefaults(params []Symbol, defaults []ast.Expression) error {
+	firstDefault := len(params) - len(defaults)
+
+	for i, d := range defaults {
+		param := params[firstDefault+i]
+		posJump := c.emit(code.OpJumpArgPassed, param.Index, 9999)
+
+		err := c.Compile(d)
+		if err != nil {
+			return err
+		}
+		c.storeSymbol(param)
+
+		afterDefault := len(c.currentInstructions())
+		c.setInstruction(posJump, code.Make(code.OpJumpArgPassed, param.Index, afterDefault))
 	}
 
 	return nil
 }
 
+func hasSpread(args []ast.Expression) bool {
+	for _, a := range args {
+		if _, ok := a.(*ast.SpreadExpression); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Compiles the arguments of a call with spread arguments into arrays: runs of
+// plain arguments are collected into one, spread ones are arrays already.
+// OpCallSpread passes the elements of the arrays as the arguments.
+func (c *Compiler) compileSpreadArguments(args []ast.Expression) error {
+	numArrays := 0
+	numPlain := 0 // plain arguments not yet collected into an array
+
+	collectPlain := func() {
+		if numPlain > 0 {
+			c.emit(code.OpArray, numPlain)
+			numArrays++
+			numPlain = 0
+		}
+	}
+
+	for _, a := range args {
+		spread, ok := a.(*ast.SpreadExpression)
+		if !ok {
+			err := c.Compile(a)
+			if err != nil {
+				return err
+			}
+			numPlain++
+			continue
+		}
+
+		collectPlain()
+		err := c.Compile(spread.Value)
+		if err != nil {
+			return err
+		}
+		numArrays++
+	}
+	collectPlain()
+
+	c.emit(code.OpCallSpread, numArrays)
+	return nil
+}
+
+// Replaces the value on the stack by its parts, then stores them into the
+// variables of the pattern.
+func (c *Compiler) compileDestructuring(pattern ast.Pattern) {
+	// shape mismatches are reported at the pattern
+	outerPos := c.pos
+	c.pos = pattern.Pos()
+	defer func() { c.pos = outerPos }()
+
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		withRest := 0
+		if pattern.Rest != nil {
+			withRest = 1
+		}
+		c.emit(code.OpDestructureArray, len(pattern.Elements), withRest)
+
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			key := &object.String{Value: pair.Key}
+			c.emit(code.OpConstant, c.addConstant(key))
+		}
+		c.emit(code.OpDestructureHash, len(pattern.Pairs))
+	}
+
+	c.storeNames(pattern.Names())
+}
+
+// Defines the names in order and stores the values on the stack into them, the
+// last name's value being on top.
+func (c *Compiler) storeNames(names []*ast.Identifier) {
+	symbols := []Symbol{}
+	for _, name := range names {
+		symbols = append(symbols, c.symbolTable.Define(name.Value))
+	}
+
+	for i := len(symbols) - 1; i >= 0; i-- {
+		c.storeSymbol(symbols[i])
+	}
+}
+
+// Tries the arms in order, each one matching the subject with OpMatch and
+// falling through to the next if the pattern or guard fails. OpNoMatch after
+// the last arm reports a subject that no arm matched.
+func (c *Compiler) compileMatchExpression(node *ast.MatchExpression) error {
+	err := c.Compile(node.Subject)
+	if err != nil {
+		return err
+	}
+
+	// the subject lives in a hidden variable, `$` can't start an identifier
+	subject := c.symbolTable.Define("$match")
+	c.storeSymbol(subject)
+
+	endJumps := []int{}
+	for _, arm := range node.Arms {
+		c.loadSymbol(subject)
+		pattern := &object.Pattern{Pattern: arm.Pattern}
+		c.emit(code.OpMatch, c.addConstant(pattern))
+		nextArmJumps := []int{c.emit(code.OpJumpNotTruthy, 9999)}
+
+		c.storeNames(arm.Pattern.Names())
+
+		if arm.Guard != nil {
+			err := c.Compile(arm.Guard)
+			if err != nil {
+				return err
+			}
+			nextArmJumps = append(nextArmJumps, c.emit(code.OpJumpNotTruthy, 9999))
+		}
+
+		err := c.Compile(arm.Body)
+		if err != nil {
+			return err
+		}
+		endJumps = append(endJumps, c.emit(code.OpJump, 9999))
+
+		for _, pos := range nextArmJumps {
+			c.changeOperand(pos, len(c.currentInstructions()))
+		}
+	}
+
+	c.loadSymbol(subject)
+	c.emit(code.OpNoMatch)
+
+	for _, pos := range endJumps {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+
+	return nil
+}
+
+// Compiles quote(...). The quoted code becomes a constant in which each
+// unquote(x) call is replaced by unquote(i), i numbering the unquoted values.
+// The unquoted expressions are compiled instead, OpQuote substitutes their
+// values when it creates the Quote at runtime.
+func (c *Compiler) compileQuote(node ast.Node) error {
+	var err error
+	numUnquoted := 0
+
+	template := ast.Modify(node, func(node ast.Node) ast.Node {
+		if err != nil || !object.IsUnquoteCall(node) {
+			return node
+		}
+
+		call := *node.(*ast.CallExpression)
+		err = c.Compile(call.Arguments[0])
+
+		index := &ast.IntegerLiteral{
+			Token: token.Token{Type: token.INT, Literal: fmt.Sprint(numUnquoted)},
+			Value: int64(numUnquoted),
+		}
+		call.Arguments = []ast.Expression{index}
+		numUnquoted++
+
+		return &call
+	})
+	if err != nil {
+		return err
+	}
+
+	quote := &object.Quote{Node: template}
+	c.emit(code.OpQuote, c.addConstant(quote), numUnquoted)
+	return nil
+}
+
 func (c *Compiler) Bytecode() *Bytecode {
 	return &Bytecode{
-		Instructions: c.instructions,
+		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
+		GlobalNames:  c.symbolTable.DefinedNames(),
+		SourceMap:    c.scopes[c.scopeIndex].sourceMap,
 	}
 }
 
@@ -190,40 +1047,139 @@ func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	ins := code.Make(op, operands...)
 	pos := c.addInstructions(ins)
 
+	scope := &c.scopes[c.scopeIndex]
+	scope.sourceMap = scope.sourceMap.Add(pos, c.pos)
+
 	c.setLastInstruction(op, pos)
 
 	return pos
 }
 
 func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
-	c.previousInstruction = c.lastInstruction
-	c.lastInstruction = EmittedInstruction{op, pos}
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
 }
 
 func (c *Compiler) addInstructions(ins []byte) int {
-	posNewInstruction := len(c.instructions)
-	c.instructions = append(c.instructions, ins...)
+	posNewInstruction := len(c.currentInstructions())
+	updatedInstructions := append(c.currentInstructions(), ins...)
+
+	c.scopes[c.scopeIndex].instructions = updatedInstructions
+
 	return posNewInstruction
 }
 
-func (c *Compiler) lastInstructionIsPop() bool {
-	return c.lastInstruction.Opcode == code.OpPop
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
 }
 
 func (c *Compiler) removeLastPop() {
-	c.instructions = c.instructions[:c.lastInstruction.Position]
-	c.lastInstruction = c.previousInstruction
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	trimmed := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = trimmed
+	c.scopes[c.scopeIndex].sourceMap = c.scopes[c.scopeIndex].sourceMap.Truncate(last.Position)
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.setInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
 }
 
 func (c *Compiler) setInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
 	for i := 0; i < len(newInstruction); i++ {
-		c.instructions[pos+i] = newInstruction[i]
+		ins[pos+i] = newInstruction[i]
 	}
 }
 
 func (c *Compiler) changeOperand(opPos int, operand int) {
-	op := code.Opcode(c.instructions[opPos])
+	op := code.Opcode(c.currentInstructions()[opPos])
 	newInstruction := code.Make(op, operand)
 
 	c.setInstruction(opPos, newInstruction)
 }
+
+// Starts compiling a new function body with its own instructions and a
+// symbol table enclosed by the current one.
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// Finishes the current function body. Returns its instructions.
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpSetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	}
+}
+
+// Pushes the cell of a variable captured by the closure being created, so
+// the closure shares it with the enclosing function.
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpCaptureLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpCaptureFree, s.Index)
+	case FunctionScope:
+		// the enclosing function itself, captured by value
+		c.emit(code.OpCurrentClosure)
+	}
+}
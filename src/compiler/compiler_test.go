@@ -2,6 +2,7 @@ package compiler
 
 import (
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/tjapit/monkey/src/ast"
@@ -77,6 +78,46 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf(
+			"object has wrong value. want=%g, got =%g",
+			expected,
+			result.Value,
+		)
+	}
+
+	return nil
+}
+
+func testStringObject(expected string, actual object.Object) error {
+	result, ok := actual.(*object.String)
+	if !ok {
+		return fmt.Errorf("object is not String. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf(
+			"object has wrong value. want=%q, got =%q",
+			expected,
+			result.Value,
+		)
+	}
+
+	return nil
+}
+
+// Expected quote constant, given by the String() of its node.
+type quoteConstant string
+
+// Expected match pattern constant, given by the String() of the pattern.
+type patternConstant string
+
 func testConstants(
 	t *testing.T,
 	expected []interface{},
@@ -97,6 +138,63 @@ func testConstants(
 			if err != nil {
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s", i, err)
 			}
+		case float64:
+			err := testFloatObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testFloatObject failed: %s", i, err)
+			}
+		case *big.Int:
+			result, ok := actual[i].(*object.BigInt)
+			if !ok || result.Value.Cmp(constant) != 0 {
+				return fmt.Errorf(
+					"constant %d - not BigInt %s. got=%T (%+v)",
+					i,
+					constant,
+					actual[i],
+					actual[i],
+				)
+			}
+		case string:
+			err := testStringObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testStringObject failed: %s", i, err)
+			}
+		case quoteConstant:
+			quote, ok := actual[i].(*object.Quote)
+			if !ok || quote.Node.String() != string(constant) {
+				return fmt.Errorf(
+					"constant %d - not Quote %q. got=%T (%+v)",
+					i,
+					constant,
+					actual[i],
+					actual[i],
+				)
+			}
+		case patternConstant:
+			pattern, ok := actual[i].(*object.Pattern)
+			if !ok || pattern.Pattern.String() != string(constant) {
+				return fmt.Errorf(
+					"constant %d - not Pattern %q. got=%T (%+v)",
+					i,
+					constant,
+					actual[i],
+					actual[i],
+				)
+			}
+		case []code.Instructions:
+			fn, ok := actual[i].(*object.CompiledFunction)
+			if !ok {
+				return fmt.Errorf(
+					"constant %d - not a function: %T",
+					i,
+					actual[i],
+				)
+			}
+
+			err := testInstructions(constant, fn.Instructions)
+			if err != nil {
+				return fmt.Errorf("constant %d - testInstructions failed: %s", i, err)
+			}
 		}
 	}
 
@@ -201,6 +299,61 @@ func TestIntegerArithmetic(t *testing.T) {
 				code.Make(code.OpPop),
 			},
 		},
+		{
+			desc:              "Test 7",
+			input:             "5 % 2",
+			expectedConstants: []interface{}{5, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMod),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "3.14",
+			expectedConstants: []interface{}{3.14},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             "1 + 2.5",
+			expectedConstants: []interface{}{1, 2.5},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestBigIntLiterals(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "-9223372036854775808",
+			expectedConstants: []interface{}{new(big.Int).Lsh(big.NewInt(1), 63)},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpMinus),
+				code.Make(code.OpPop),
+			},
+		},
 	}
 
 	runCompilerTests(t, testCases)
@@ -240,11 +393,11 @@ func TestBooleanExpression(t *testing.T) {
 		{
 			desc:              "Test 4",
 			input:             "1 < 2",
-			expectedConstants: []interface{}{2, 1},
+			expectedConstants: []interface{}{1, 2},
 			expectedInstructions: []code.Instructions{
 				code.Make(code.OpConstant, 0),
 				code.Make(code.OpConstant, 1),
-				code.Make(code.OpGreaterThan),
+				code.Make(code.OpLessThan),
 				code.Make(code.OpPop),
 			},
 		},
@@ -302,6 +455,76 @@ func TestBooleanExpression(t *testing.T) {
 				code.Make(code.OpPop),
 			},
 		},
+		{
+			desc:              "Test 10",
+			input:             "1 >= 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpGreaterThanOrEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 11",
+			input:             "1 <= 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpLessThanOrEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 12",
+			input:             "true && false",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 12),
+				// 0004
+				code.Make(code.OpFalse),
+				// 0005
+				code.Make(code.OpJumpNotTruthy, 12),
+				// 0008
+				code.Make(code.OpTrue),
+				// 0009
+				code.Make(code.OpJump, 13),
+				// 0012
+				code.Make(code.OpFalse),
+				// 0013
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 13",
+			input:             "true || false",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 7),
+				// 0004
+				code.Make(code.OpJump, 11),
+				// 0007
+				code.Make(code.OpFalse),
+				// 0008
+				code.Make(code.OpJumpNotTruthy, 15),
+				// 0011
+				code.Make(code.OpTrue),
+				// 0012
+				code.Make(code.OpJump, 16),
+				// 0015
+				code.Make(code.OpFalse),
+				// 0016
+				code.Make(code.OpPop),
+			},
+		},
 	}
 
 	runCompilerTests(t, testCases)
@@ -319,14 +542,18 @@ func TestConditionals(t *testing.T) {
 				// 0000
 				code.Make(code.OpTrue),
 				// 0001
-				code.Make(code.OpJumpNotTruthy, 7),
+				code.Make(code.OpJumpNotTruthy, 10),
 				// 0004
 				code.Make(code.OpConstant, 0),
 				// 0007
+				code.Make(code.OpJump, 11),
+				// 0010 | implicit null Alternative
+				code.Make(code.OpNull),
+				// 0011
 				code.Make(code.OpPop),
-				// 0008
+				// 0012
 				code.Make(code.OpConstant, 1),
-				// 0011
+				// 0015
 				code.Make(code.OpPop),
 			},
 		},
@@ -359,3 +586,1498 @@ func TestConditionals(t *testing.T) {
 
 	runCompilerTests(t, testCases)
 }
+
+func TestLoops(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "while (true) { 1; }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 11),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpPop),
+				// 0008
+				code.Make(code.OpJump, 0),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             "while (true) { break; continue; }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 13),
+				// 0004 | break
+				code.Make(code.OpJump, 13),
+				// 0007 | continue
+				code.Make(code.OpJump, 0),
+				// 0010
+				code.Make(code.OpJump, 0),
+			},
+		},
+		{
+			desc:              "Test 3",
+			input:             "for (x in [1]) { x; }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpIterInit),
+				// 0007 | hidden iterator variable
+				code.Make(code.OpSetGlobal, 0),
+				// 0010
+				code.Make(code.OpGetGlobal, 0),
+				// 0013
+				code.Make(code.OpIterNext, 1),
+				// 0015
+				code.Make(code.OpJumpNotTruthy, 28),
+				// 0018
+				code.Make(code.OpSetGlobal, 1),
+				// 0021
+				code.Make(code.OpGetGlobal, 1),
+				// 0024
+				code.Make(code.OpPop),
+				// 0025
+				code.Make(code.OpJump, 10),
+			},
+		},
+		{
+			desc:  "Test 4",
+			input: "fn() { for (k, v in {}) { continue; } }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					// 0000
+					code.Make(code.OpHash, 0),
+					// 0003
+					code.Make(code.OpIterInit),
+					// 0004
+					code.Make(code.OpSetLocal, 0),
+					// 0006
+					code.Make(code.OpGetLocal, 0),
+					// 0008
+					code.Make(code.OpIterNext, 2),
+					// 0010
+					code.Make(code.OpJumpNotTruthy, 23),
+					// 0013 | v
+					code.Make(code.OpSetLocal, 1),
+					// 0015 | k
+					code.Make(code.OpSetLocal, 2),
+					// 0017 | continue
+					code.Make(code.OpJump, 6),
+					// 0020
+					code.Make(code.OpJump, 6),
+					// 0023
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestTryExpressions(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "try { 1 } catch (e) { 2 }",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTry, 10),
+				// 0003
+				code.Make(code.OpConstant, 0),
+				// 0006
+				code.Make(code.OpEndTry),
+				// 0007
+				code.Make(code.OpJump, 16),
+				// 0010 | catch
+				code.Make(code.OpSetGlobal, 0),
+				// 0013
+				code.Make(code.OpConstant, 1),
+				// 0016
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             "try { 1 } finally { 2 }",
+			expectedConstants: []interface{}{1, 2, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTry, 14),
+				// 0003
+				code.Make(code.OpConstant, 0),
+				// 0006
+				code.Make(code.OpEndTry),
+				// 0007 | finally
+				code.Make(code.OpConstant, 1),
+				// 0010
+				code.Make(code.OpPop),
+				// 0011
+				code.Make(code.OpJump, 25),
+				// 0014 | finally, then rethrow
+				code.Make(code.OpSetGlobal, 0),
+				// 0017
+				code.Make(code.OpConstant, 2),
+				// 0020
+				code.Make(code.OpPop),
+				// 0021
+				code.Make(code.OpGetGlobal, 0),
+				// 0024
+				code.Make(code.OpThrow),
+				// 0025
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 3",
+			input:             "try { 1 } catch (e) { 2 } finally { 3 }",
+			expectedConstants: []interface{}{1, 2, 3, 3},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTry, 10),
+				// 0003
+				code.Make(code.OpConstant, 0),
+				// 0006
+				code.Make(code.OpEndTry),
+				// 0007
+				code.Make(code.OpJump, 20),
+				// 0010 | catch
+				code.Make(code.OpSetGlobal, 0),
+				// 0013
+				code.Make(code.OpTry, 27),
+				// 0016
+				code.Make(code.OpConstant, 1),
+				// 0019
+				code.Make(code.OpEndTry),
+				// 0020 | finally
+				code.Make(code.OpConstant, 2),
+				// 0023
+				code.Make(code.OpPop),
+				// 0024
+				code.Make(code.OpJump, 38),
+				// 0027 | finally, then rethrow
+				code.Make(code.OpSetGlobal, 1),
+				// 0030
+				code.Make(code.OpConstant, 3),
+				// 0033
+				code.Make(code.OpPop),
+				// 0034
+				code.Make(code.OpGetGlobal, 1),
+				// 0037
+				code.Make(code.OpThrow),
+				// 0038
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 4",
+			input:             `throw "x";`,
+			expectedConstants: []interface{}{"x"},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpThrow),
+			},
+		},
+		{
+			desc:  "Test 5",
+			input: "fn() { try { return 1; } finally { 2 } }",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				2,
+				2,
+				[]code.Instructions{
+					// 0000
+					code.Make(code.OpTry, 21),
+					// 0003
+					code.Make(code.OpConstant, 0),
+					// 0006 | leave the try region before returning
+					code.Make(code.OpEndTry),
+					// 0007
+					code.Make(code.OpConstant, 1),
+					// 0010
+					code.Make(code.OpPop),
+					// 0011
+					code.Make(code.OpReturnValue),
+					// 0012
+					code.Make(code.OpNull),
+					// 0013
+					code.Make(code.OpEndTry),
+					// 0014 | finally
+					code.Make(code.OpConstant, 2),
+					// 0017
+					code.Make(code.OpPop),
+					// 0018
+					code.Make(code.OpJump, 30),
+					// 0021 | finally, then rethrow
+					code.Make(code.OpSetLocal, 0),
+					// 0023
+					code.Make(code.OpConstant, 3),
+					// 0026
+					code.Make(code.OpPop),
+					// 0027
+					code.Make(code.OpGetLocal, 0),
+					// 0029
+					code.Make(code.OpThrow),
+					// 0030
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 4, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestSourceMap(t *testing.T) {
+	input := "let x = 1;\nx + true;\nfn() {\n  -x\n};"
+
+	program := parse(input)
+
+	compiler := New()
+	err := compiler.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	bytecode := compiler.Bytecode()
+	fn, ok := bytecode.Constants[1].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 1 is not CompiledFunction. got=%T", bytecode.Constants[1])
+	}
+
+	testCases := []struct {
+		desc      string
+		sourceMap code.SourceMap
+		offset    int
+		expected  string
+	}{
+		{"Test 1", bytecode.SourceMap, 0, "1:9"},  // OpConstant
+		{"Test 2", bytecode.SourceMap, 3, "1:1"},  // OpSetGlobal
+		{"Test 3", bytecode.SourceMap, 6, "2:1"},  // OpGetGlobal
+		{"Test 4", bytecode.SourceMap, 9, "2:5"},  // OpTrue
+		{"Test 5", bytecode.SourceMap, 10, "2:3"}, // OpAdd
+		{"Test 6", bytecode.SourceMap, 11, "2:1"}, // OpPop
+		{"Test 7", fn.SourceMap, 0, "4:4"},        // OpGetGlobal
+		{"Test 8", fn.SourceMap, 3, "4:3"},        // OpMinus
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			pos := tC.sourceMap.Lookup(tC.offset)
+			if pos.String() != tC.expected {
+				t.Errorf("wrong position. want=%s, got =%s", tC.expected, pos)
+			}
+		})
+	}
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc: "Test 1",
+			input: `
+      let one = 1;
+      let two = 2;
+      `,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
+		{
+			desc: "Test 2",
+			input: `
+      let one = 1;
+      one;
+      `,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc: "Test 3",
+			input: `
+      let one = 1;
+      let two = one;
+      two;
+      `,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestUndefinedVariable(t *testing.T) {
+	program := parse("let a = b;")
+
+	compiler := New()
+	err := compiler.Compile(program)
+	if err == nil {
+		t.Fatalf("expected compiler error, got none")
+	}
+
+	expected := "1:9: undefined variable b"
+	if err.Error() != expected {
+		t.Fatalf("wrong error message. want=%q, got =%q", expected, err.Error())
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "let x = 1; x = 2;",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             "let x = 1; x += 2;",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 3",
+			input: "fn() { let x = 1; x -= 2; }",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSub),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 4",
+			input:             "let a = [1]; a[0] = 2;",
+			expectedConstants: []interface{}{1, 0, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpSetIndex),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 5",
+			input:             "let a = [1]; a[0] *= 2;",
+			expectedConstants: []interface{}{1, 0, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDupPair),
+				code.Make(code.OpIndex),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpMul),
+				code.Make(code.OpSetIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestAssignErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "x = 1;", "1:3: cannot assign to undeclared variable x"},
+		{"Test 2", "len = 1;", "1:5: cannot assign to builtin len"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			program := parse(tC.input)
+
+			compiler := New()
+			err := compiler.Compile(program)
+			if err == nil {
+				t.Fatalf("expected compiler error, got none")
+			}
+
+			if err.Error() != tC.expected {
+				t.Fatalf("wrong error message. want=%q, got =%q", tC.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestStringExpressions(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             `"monkey"`,
+			expectedConstants: []interface{}{"monkey"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             `"mon" + "key"`,
+			expectedConstants: []interface{}{"mon", "key"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestArrayLiterals(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "[]",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             "[1, 2, 3]",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpArray, 3),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 3",
+			input:             "[1 + 2, 3 - 4]",
+			expectedConstants: []interface{}{1, 2, 3, 4},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpSub),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestHashLiterals(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "{}",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpHash, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             "{1: 2, 3: 4, 5: 6}",
+			expectedConstants: []interface{}{1, 2, 3, 4, 5, 6},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpConstant, 5),
+				code.Make(code.OpHash, 6),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 3",
+			input:             "{1: 2 + 3, 4: 5 * 6}",
+			expectedConstants: []interface{}{1, 2, 3, 4, 5, 6},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpConstant, 5),
+				code.Make(code.OpMul),
+				code.Make(code.OpHash, 4),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "[1, 2, 3][1 + 1]",
+			expectedConstants: []interface{}{1, 2, 3, 1, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpArray, 3),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpAdd),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             "{1: 2}[2 - 1]",
+			expectedConstants: []interface{}{1, 2, 2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpHash, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpSub),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestFunctions(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:  "Test 1",
+			input: "fn() { return 5 + 10 }",
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 2",
+			input: "fn() { 5 + 10 }",
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 3",
+			input: "fn() { 1; 2 }",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpPop),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 4",
+			input: "fn() { }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestCompilerScopes(t *testing.T) {
+	compiler := New()
+	if compiler.scopeIndex != 0 {
+		t.Errorf("scopeIndex wrong. want=%d, got =%d", 0, compiler.scopeIndex)
+	}
+	globalSymbolTable := compiler.symbolTable
+
+	compiler.emit(code.OpMul)
+
+	compiler.enterScope()
+	if compiler.scopeIndex != 1 {
+		t.Errorf("scopeIndex wrong. want=%d, got =%d", 1, compiler.scopeIndex)
+	}
+
+	compiler.emit(code.OpSub)
+
+	if len(compiler.scopes[compiler.scopeIndex].instructions) != 1 {
+		t.Errorf(
+			"instructions length wrong. got =%d",
+			len(compiler.scopes[compiler.scopeIndex].instructions),
+		)
+	}
+
+	last := compiler.scopes[compiler.scopeIndex].lastInstruction
+	if last.Opcode != code.OpSub {
+		t.Errorf(
+			"lastInstruction.Opcode wrong. want=%d, got =%d",
+			code.OpSub,
+			last.Opcode,
+		)
+	}
+
+	if compiler.symbolTable.Outer != globalSymbolTable {
+		t.Errorf("compiler did not enclose symbolTable")
+	}
+
+	compiler.leaveScope()
+	if compiler.scopeIndex != 0 {
+		t.Errorf("scopeIndex wrong. want=%d, got =%d", 0, compiler.scopeIndex)
+	}
+
+	if compiler.symbolTable != globalSymbolTable {
+		t.Errorf("compiler did not restore global symbol table")
+	}
+	if compiler.symbolTable.Outer != nil {
+		t.Errorf("compiler modified global symbol table incorrectly")
+	}
+
+	compiler.emit(code.OpAdd)
+
+	if len(compiler.scopes[compiler.scopeIndex].instructions) != 2 {
+		t.Errorf(
+			"instructions length wrong. got =%d",
+			len(compiler.scopes[compiler.scopeIndex].instructions),
+		)
+	}
+
+	last = compiler.scopes[compiler.scopeIndex].lastInstruction
+	if last.Opcode != code.OpAdd {
+		t.Errorf(
+			"lastInstruction.Opcode wrong. want=%d, got =%d",
+			code.OpAdd,
+			last.Opcode,
+		)
+	}
+
+	previous := compiler.scopes[compiler.scopeIndex].previousInstruction
+	if previous.Opcode != code.OpMul {
+		t.Errorf(
+			"previousInstruction.Opcode wrong. want=%d, got =%d",
+			code.OpMul,
+			previous.Opcode,
+		)
+	}
+}
+
+func TestFunctionCalls(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:  "Test 1",
+			input: "fn() { 24 }();",
+			expectedConstants: []interface{}{
+				24,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc: "Test 2",
+			input: `
+      let oneArg = fn(a) { a };
+      oneArg(24);
+      `,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpReturnValue),
+				},
+				24,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc: "Test 3",
+			input: `
+      let manyArg = fn(a, b, c) { a; b; c };
+      manyArg(24, 25, 26);
+      `,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetLocal, 2),
+					code.Make(code.OpReturnValue),
+				},
+				24,
+				25,
+				26,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpCall, 3),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestLetStatementScopes(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc: "Test 1",
+			input: `
+      let num = 55;
+      fn() { num }
+      `,
+			expectedConstants: []interface{}{
+				55,
+				[]code.Instructions{
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc: "Test 2",
+			input: `
+      fn() {
+        let a = 55;
+        let b = 77;
+        a + b
+      }
+      `,
+			expectedConstants: []interface{}{
+				55,
+				77,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestClosures(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc: "Test 1",
+			input: `
+      fn(a) {
+        fn(b) {
+          a + b
+        }
+      }
+      `,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc: "Test 2",
+			input: `
+      fn(a) {
+        fn(b) {
+          fn(c) {
+            a + b + c
+          }
+        }
+      };
+      `,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetFree, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpCaptureFree, 0),
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 0, 2),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 1, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 3",
+			input: "fn() { let x = 1; fn() { x = 2; } }",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetFree, 0),
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 2, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc: "Test 1",
+			input: `
+      let countDown = fn(x) { countDown(x - 1); };
+      countDown(1);
+      `,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpCurrentClosure),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSub),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+				1,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestBuiltins(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc: "Test 1",
+			input: `
+      len([]);
+      push([], 1);
+      `,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetBuiltin, 0),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetBuiltin, 5),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpCall, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 2",
+			input: "fn() { len([]) }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetBuiltin, 0),
+					code.Make(code.OpArray, 0),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestQuote(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "quote(1 + 2)",
+			expectedConstants: []interface{}{quoteConstant("(1 + 2)")},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpQuote, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 2",
+			input: "quote(unquote(1 + 2) * unquote(3))",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				3,
+				quoteConstant("(unquote(0) * unquote(1))"),
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpQuote, 3, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestDestructuring(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             "let [a, b] = [1, 2];",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpConstant, 1),
+				// 0006
+				code.Make(code.OpArray, 2),
+				// 0009
+				code.Make(code.OpDestructureArray, 2, 0),
+				// 0013
+				code.Make(code.OpSetGlobal, 1),
+				// 0016
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             "let [a, ...rest] = []; rest",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpArray, 0),
+				// 0003
+				code.Make(code.OpDestructureArray, 1, 1),
+				// 0007
+				code.Make(code.OpSetGlobal, 1),
+				// 0010
+				code.Make(code.OpSetGlobal, 0),
+				// 0013
+				code.Make(code.OpGetGlobal, 1),
+				// 0016
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 3",
+			input:             `let {name, age} = {};`,
+			expectedConstants: []interface{}{"name", "age"},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpHash, 0),
+				// 0003
+				code.Make(code.OpConstant, 0),
+				// 0006
+				code.Make(code.OpConstant, 1),
+				// 0009
+				code.Make(code.OpDestructureHash, 2),
+				// 0012
+				code.Make(code.OpSetGlobal, 1),
+				// 0015
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+		{
+			desc:  "Test 4",
+			input: "fn(pair) { let [a, b] = pair; a }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpDestructureArray, 2, 0),
+					code.Make(code.OpSetLocal, 2),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestImport(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:              "Test 1",
+			input:             `import("lib.mk")`,
+			expectedConstants: []interface{}{"lib.mk"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpImport, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             `export let m = import("lib.mk"); m["x"]`,
+			expectedConstants: []interface{}{"lib.mk", "x"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpImport, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestCompilerWithState(t *testing.T) {
+	symbolTable := NewSymbolTable()
+	constants := []object.Object{}
+
+	first := NewWithState(symbolTable, constants)
+	err := first.Compile(parse("let a = 1;"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	constants = first.Bytecode().Constants
+
+	second := NewWithState(symbolTable, constants)
+	err = second.Compile(parse("a + 2;"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	bytecode := second.Bytecode()
+
+	err = testInstructions(
+		[]code.Instructions{
+			code.Make(code.OpGetGlobal, 0),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpAdd),
+			code.Make(code.OpPop),
+		},
+		bytecode.Instructions,
+	)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	err = testConstants(t, []interface{}{1, 2}, bytecode.Constants)
+	if err != nil {
+		t.Fatalf("testConstants failed: %s", err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:  "Test 1",
+			input: "match (1) { 2 => 3, _ => 4 };",
+			expectedConstants: []interface{}{
+				1,
+				patternConstant("2"),
+				3,
+				patternConstant("_"),
+				4,
+			},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpSetGlobal, 0),
+				// 0006
+				code.Make(code.OpGetGlobal, 0),
+				// 0009
+				code.Make(code.OpMatch, 1),
+				// 0012
+				code.Make(code.OpJumpNotTruthy, 21),
+				// 0015
+				code.Make(code.OpConstant, 2),
+				// 0018
+				code.Make(code.OpJump, 40),
+				// 0021
+				code.Make(code.OpGetGlobal, 0),
+				// 0024
+				code.Make(code.OpMatch, 3),
+				// 0027
+				code.Make(code.OpJumpNotTruthy, 36),
+				// 0030
+				code.Make(code.OpConstant, 4),
+				// 0033
+				code.Make(code.OpJump, 40),
+				// 0036
+				code.Make(code.OpGetGlobal, 0),
+				// 0039
+				code.Make(code.OpNoMatch),
+				// 0040
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:              "Test 2",
+			input:             "match ([1]) { [x] if x => x };",
+			expectedConstants: []interface{}{1, patternConstant("[x]")},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpSetGlobal, 0),
+				// 0009
+				code.Make(code.OpGetGlobal, 0),
+				// 0012
+				code.Make(code.OpMatch, 1),
+				// 0015
+				code.Make(code.OpJumpNotTruthy, 33),
+				// 0018
+				code.Make(code.OpSetGlobal, 1),
+				// 0021
+				code.Make(code.OpGetGlobal, 1),
+				// 0024
+				code.Make(code.OpJumpNotTruthy, 33),
+				// 0027
+				code.Make(code.OpGetGlobal, 1),
+				// 0030
+				code.Make(code.OpJump, 37),
+				// 0033
+				code.Make(code.OpGetGlobal, 0),
+				// 0036
+				code.Make(code.OpNoMatch),
+				// 0037
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 3",
+			input: "fn(p) { match (p) { {a, b} => a } }",
+			expectedConstants: []interface{}{
+				patternConstant("{a, b}"),
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpMatch, 0),
+					code.Make(code.OpJumpNotTruthy, 21),
+					code.Make(code.OpSetLocal, 3),
+					code.Make(code.OpSetLocal, 2),
+					code.Make(code.OpGetLocal, 2),
+					code.Make(code.OpJump, 24),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpNoMatch),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestDefaultParameters(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:  "Test 1",
+			input: "fn(a, b = 2) { b }",
+			expectedConstants: []interface{}{
+				2,
+				[]code.Instructions{
+					// 0000
+					code.Make(code.OpJumpArgPassed, 1, 9),
+					// 0004
+					code.Make(code.OpConstant, 0),
+					// 0007
+					code.Make(code.OpSetLocal, 1),
+					// 0009
+					code.Make(code.OpGetLocal, 1),
+					// 0011
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 2",
+			input: "fn(a = 1, ...rest) { rest }",
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					// 0000
+					code.Make(code.OpJumpArgPassed, 0, 9),
+					// 0004
+					code.Make(code.OpConstant, 0),
+					// 0007
+					code.Make(code.OpSetLocal, 0),
+					// 0009
+					code.Make(code.OpGetLocal, 1),
+					// 0011
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
+
+func TestSpreadArguments(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			desc:  "Test 1",
+			input: "let f = fn() {}; f(...[]);",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpCallSpread, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			desc:  "Test 2",
+			input: "let f = fn() {}; f(1, ...[2], 3, 4);",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpReturn),
+				},
+				1,
+				2,
+				3,
+				4,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpCallSpread, 3),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, testCases)
+}
@@ -0,0 +1,133 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+	BuiltinScope  SymbolScope = "BUILTIN"
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+	names          []string // defined names by index
+
+	FreeSymbols []Symbol // original symbols of the captured free variables
+}
+
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Associates the identifier with a new Symbol. Returns the created Symbol.
+func (s *SymbolTable) Define(name string) Symbol {
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	s.names = append(s.names, name)
+	return symbol
+}
+
+// Returns a copy of the table that later definitions in either table don't
+// affect.
+func (s *SymbolTable) Copy() *SymbolTable {
+	c := NewEnclosedSymbolTable(s.Outer)
+	for name, symbol := range s.store {
+		c.store[name] = symbol
+	}
+	c.numDefinitions = s.numDefinitions
+	c.names = append([]string{}, s.names...)
+	c.FreeSymbols = append([]Symbol{}, s.FreeSymbols...)
+	return c
+}
+
+// Returns the names of the symbols created by Define, by index. A name defined
+// twice appears at both indexes.
+func (s *SymbolTable) DefinedNames() []string {
+	return s.names
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// Defines the name of the function currently being compiled, so it can call
+// itself without capturing itself as a free variable.
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{
+		Name:  original.Name,
+		Index: len(s.FreeSymbols) - 1,
+		Scope: FreeScope,
+	}
+
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+// Resolves the identifier in this or any enclosing table. Locals of enclosing
+// functions are turned into free variables of this one.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		return s.resolveOuter(name)
+	}
+	return symbol, ok
+}
+
+// Resolves the variable the function being compiled is bound to, which its
+// name refers to when assigned to.
+func (s *SymbolTable) ResolveFunctionBinding(name string) (Symbol, bool) {
+	if s.Outer == nil {
+		return Symbol{}, false
+	}
+	return s.resolveOuter(name)
+}
+
+func (s *SymbolTable) resolveOuter(name string) (Symbol, bool) {
+	symbol, ok := s.Outer.Resolve(name)
+	if !ok {
+		return symbol, ok
+	}
+
+	if symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+		return symbol, ok
+	}
+
+	free := s.defineFree(symbol)
+	return free, true
+}
@@ -2,8 +2,12 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 
 	"github.com/tjapit/monkey/src/ast"
+	"github.com/tjapit/monkey/src/module"
 	"github.com/tjapit/monkey/src/object"
 )
 
@@ -11,9 +15,24 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := evalNode(node, env)
+
+	// errors are created deep inside helpers that don't know about nodes, so
+	// the innermost node they surface from is the one they're attributed to
+	if errObj, ok := result.(*object.Error); ok && !errObj.Pos.IsValid() {
+		errObj.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
@@ -28,16 +47,37 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return object.NewThrownError(val)
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			return evalDestructuring(node.Pattern, val, env)
+		}
 		env.Set(node.Name.Value, val)
 
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.BigIntLiteral:
+		return &object.BigInt{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.Identifier:
@@ -47,8 +87,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return evalPrefixExpression(node.Operator, right, env)
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -59,22 +103,42 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return evalInfixExpression(node.Operator, left, right, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
 	case *ast.FunctionLiteral:
 		return &object.Function{
 			Parameters: node.Parameters,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
 			Body:       node.Body,
 			Env:        env,
 		}
+	case *ast.MacroLiteral:
+		return newError("macros must be defined by top-level let statements")
+	case *ast.ImportExpression:
+		return evalImportExpression(node, env)
+	case *ast.SpreadExpression:
+		// call arguments are expanded by evalArguments, spreads reach here only
+		// when macros splice them elsewhere
+		return newError("spread not allowed here")
 	case *ast.CallExpression:
+		if object.IsQuoteCall(node) {
+			return quote(node.Arguments[0], env)
+		}
+
 		fn := Eval(node.Function, env)
 		if isError(fn) {
 			return fn
 		}
 
-		args := evalExpressions(node.Arguments, env)
+		args := evalArguments(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
@@ -140,10 +204,10 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 
-		switch result := result.(type) {
-		case *object.ReturnValue:
-			return result.Value
-		case *object.Error:
+		if returnValue, ok := result.(*object.ReturnValue); ok {
+			return returnValue.Value
+		}
+		if isError(result) {
 			return result
 		}
 	}
@@ -162,7 +226,8 @@ func evalBlockStatement(
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || isError(result) ||
+				rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -171,6 +236,78 @@ func evalBlockStatement(
 	return result
 }
 
+func evalWhileStatement(
+	node *ast.WhileStatement,
+	env *object.Environment,
+) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		result := Eval(node.Body, env)
+		if isLoopExit(result) {
+			return unwrapLoopExit(result)
+		}
+	}
+}
+
+func evalForStatement(
+	node *ast.ForStatement,
+	env *object.Environment,
+) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	it, ok := object.NewIterator(iterable)
+	if !ok {
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	for it.Next() {
+		if len(node.Names) == 2 {
+			env.Set(node.Names[0].Value, it.Key())
+			env.Set(node.Names[1].Value, it.Value())
+		} else {
+			env.Set(node.Names[0].Value, it.Element())
+		}
+
+		result := Eval(node.Body, env)
+		if isLoopExit(result) {
+			return unwrapLoopExit(result)
+		}
+	}
+
+	return nil
+}
+
+// Reports whether the result of a loop body ends the loop, i.e. it's a break,
+// a return or an error.
+func isLoopExit(result object.Object) bool {
+	if result == nil {
+		return false
+	}
+
+	rt := result.Type()
+	return rt == object.BREAK_OBJ || rt == object.RETURN_VALUE_OBJ ||
+		isError(result)
+}
+
+// Consumes a break, return values and errors propagate out of the loop.
+func unwrapLoopExit(result object.Object) object.Object {
+	if result.Type() == object.BREAK_OBJ {
+		return nil
+	}
+	return result
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -191,12 +328,16 @@ func evalIdentifier(
 	return newError("identifier not found: %s", ident.Value)
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func evalPrefixExpression(
+	operator string,
+	right object.Object,
+	env *object.Environment,
+) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return evalMinusPrefixOperatorExpression(right, env)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -215,22 +356,67 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+func evalMinusPrefixOperatorExpression(
+	right object.Object,
+	env *object.Environment,
+) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		result, ok := object.NegInt64(right.Value)
+		if !ok {
+			if env.Config().CheckedArithmetic {
+				return newError("integer overflow: -(%d)", right.Value)
+			}
+			return object.NewInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: result}
+	case *object.BigInt:
+		return object.NewInteger(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
+}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+// Evaluates `&&` and `||`, only evaluating the right operand when the left one
+// doesn't decide the result. Produces a Boolean.
+func evalLogicalExpression(
+	node *ast.InfixExpression,
+	env *object.Environment,
+) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	return nativeBoolToBooleanObject(isTruthy(right))
 }
 
 func evalInfixExpression(
 	operator string,
 	left, right object.Object,
+	env *object.Environment,
 ) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, env)
+	case isInteger(left) && isInteger(right): // at least one is a BigInt
+		return evalBigIntInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right): // at least one is a float
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
@@ -258,7 +444,17 @@ func evalStringInfixExpression(
 	operator string,
 	left, right object.Object,
 ) object.Object {
-	if operator != "+" {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
 		return newError(
 			"unknown operator: %s %s %s",
 			left.Type(),
@@ -266,35 +462,175 @@ func evalStringInfixExpression(
 			right.Type(),
 		)
 	}
+}
 
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
+// Division and modulo by zero are errors. Overflowing results are promoted to
+// BigInts, unless the CheckedArithmetic setting is on.
+func evalIntegerArithmetic(
+	operator string,
+	left, right int64,
+	env *object.Environment,
+) object.Object {
+	var result int64
+	ok := true
+
+	switch operator {
+	case "+":
+		result, ok = object.AddInt64(left, right)
+	case "-":
+		result, ok = object.SubInt64(left, right)
+	case "*":
+		result, ok = object.MulInt64(left, right)
+	case "/":
+		if right == 0 {
+			return newError("division by zero")
+		}
+		result, ok = object.DivInt64(left, right)
+	case "%":
+		if right == 0 {
+			return newError("modulo by zero")
+		}
+		result = left % right
+	}
+
+	if !ok {
+		if env.Config().CheckedArithmetic {
+			return newError("integer overflow: %d %s %d", left, operator, right)
+		}
+		return evalBigIntArithmetic(operator, big.NewInt(left), big.NewInt(right))
+	}
+	return &object.Integer{Value: result}
+}
+
+func evalBigIntArithmetic(operator string, left, right *big.Int) object.Object {
+	result := new(big.Int)
+
+	switch operator {
+	case "+":
+		result.Add(left, right)
+	case "-":
+		result.Sub(left, right)
+	case "*":
+		result.Mul(left, right)
+	case "/":
+		if right.Sign() == 0 {
+			return newError("division by zero")
+		}
+		result.Quo(left, right) // truncated like int64 division
+	case "%":
+		if right.Sign() == 0 {
+			return newError("modulo by zero")
+		}
+		result.Rem(left, right)
+	}
+
+	return object.NewInteger(result)
 }
 
 func evalIntegerInfixExpression(
 	operator string,
 	left, right object.Object,
+	env *object.Environment,
 ) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	switch operator {
+	// Arithmetic
+	case "+", "-", "*", "/", "%":
+		return evalIntegerArithmetic(operator, leftVal, rightVal, env)
+
+	// Boolean
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(
+			"unknown operator: %s %s %s",
+			left.Type(),
+			operator,
+			right.Type(),
+		)
+	}
+}
+
+// Evaluates arithmetic and comparison on integers of which at least one is a
+// BigInt. Results that fit into an int64 are demoted to Integers.
+func evalBigIntInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftVal := object.BigValue(left)
+	rightVal := object.BigValue(right)
+
+	switch operator {
+	// Arithmetic
+	case "+", "-", "*", "/", "%":
+		return evalBigIntArithmetic(operator, leftVal, rightVal)
+
+	// Boolean
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError(
+			"unknown operator: %s %s %s",
+			left.Type(),
+			operator,
+			right.Type(),
+		)
+	}
+}
+
+// Evaluates arithmetic and comparison on floats. An integer operand is
+// promoted to a float first.
+func evalFloatInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
 	switch operator {
 	// Arithmetic
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return &object.Float{Value: leftVal + rightVal}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return &object.Float{Value: leftVal - rightVal}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return &object.Float{Value: leftVal * rightVal}
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
 
 	// Boolean
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -309,6 +645,148 @@ func evalIntegerInfixExpression(
 	}
 }
 
+func isNumber(obj object.Object) bool {
+	return isInteger(obj) || obj.Type() == object.FLOAT_OBJ
+}
+
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIGINT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	default:
+		return obj.(*object.Float).Value
+	}
+}
+
+func evalAssignExpression(
+	node *ast.AssignExpression,
+	env *object.Environment,
+) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		current, ok := env.Get(target.Value)
+		if !ok {
+			if _, ok := builtins[target.Value]; ok {
+				return newError("cannot assign to builtin %s", target.Value)
+			}
+			return newError("cannot assign to undeclared variable %s", target.Value)
+		}
+
+		val := evalAssignedValue(node, current, env)
+		if isError(val) {
+			return val
+		}
+
+		env.Assign(target.Value, val)
+		return val
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		var current object.Object
+		if node.Operator != "=" {
+			current = evalIndexExpression(left, index)
+			if isError(current) {
+				return current
+			}
+		}
+
+		val := evalAssignedValue(node, current, env)
+		if isError(val) {
+			return val
+		}
+
+		return evalIndexAssignment(left, index, val)
+
+	default:
+		return newError("invalid assignment target: %s", node.Target)
+	}
+}
+
+// Evaluates the right-hand side of an assignment. Compound operators like `+=`
+// combine it with the current value of the target.
+func evalAssignedValue(
+	node *ast.AssignExpression,
+	current object.Object,
+	env *object.Environment,
+) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) || node.Operator == "=" {
+		return val
+	}
+
+	operator := strings.TrimSuffix(node.Operator, "=")
+	return evalInfixExpression(operator, current, val, env)
+}
+
+func evalIndexAssignment(left, index, val object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		array := left.(*object.Array)
+		idx := index.(*object.Integer).Value
+		if idx < 0 || idx >= int64(len(array.Elements)) {
+			return newError("index out of range: %d", idx)
+		}
+
+		array.Elements[idx] = val
+		return val
+	case left.Type() == object.HASH_OBJ:
+		hash := left.(*object.Hash)
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+
+		hash.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: val}
+		return val
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+}
+
+// Evaluates the try block, then the catch block if it threw an error. The
+// finally block always runs last. Its value is discarded unless it throws,
+// returns or breaks out of a loop itself.
+func evalTryExpression(
+	node *ast.TryExpression,
+	env *object.Environment,
+) object.Object {
+	result := Eval(node.Block, env)
+
+	if isError(result) && node.Catch != nil {
+		env.Set(node.CatchName.Value, result.(*object.Error).WithThrown(false))
+		result = Eval(node.Catch, env)
+	}
+
+	if node.Finally != nil {
+		finally := Eval(node.Finally, env)
+		if isError(finally) || isLoopExit(finally) ||
+			(finally != nil && finally.Type() == object.CONTINUE_OBJ) {
+			return finally
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
 func evalIfExpression(
 	ie *ast.IfExpression,
 	env *object.Environment,
@@ -327,6 +805,44 @@ func evalIfExpression(
 	}
 }
 
+// Evaluates the body of the first arm that matches the subject. The names
+// bound by a pattern are set in env before its guard is evaluated, like let
+// statements would.
+func evalMatchExpression(
+	me *ast.MatchExpression,
+	env *object.Environment,
+) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		values, ok := object.Match(arm.Pattern, subject)
+		if !ok {
+			continue
+		}
+
+		for i, name := range arm.Pattern.Names() {
+			env.Set(name.Value, values[i])
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, env)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, env)
+	}
+
+	return newError("no match arm for %s", subject.Inspect())
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -341,12 +857,18 @@ func isTruthy(obj object.Object) bool {
 }
 
 func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return &object.Error{
+		Kind:    object.RUNTIME_ERROR,
+		Message: fmt.Sprintf(format, a...),
+		Thrown:  true,
+	}
 }
 
+// Reports whether obj is a thrown error, which aborts evaluation until it's
+// caught. Errors held as values don't.
 func isError(obj object.Object) bool {
-	if obj != nil {
-		return obj.Type() == object.ERROR_OBJ
+	if errObj, ok := obj.(*object.Error); ok {
+		return errObj.Thrown
 	}
 	return false
 }
@@ -368,28 +890,101 @@ func evalExpressions(
 	return result
 }
 
+// Evaluates the arguments of a call like evalExpressions, expanding spread
+// arguments into their elements.
+func evalArguments(
+	exps []ast.Expression,
+	env *object.Environment,
+) []object.Object {
+	var result []object.Object
+
+	for _, e := range exps {
+		spread, ok := e.(*ast.SpreadExpression)
+		if !ok {
+			evaluated := Eval(e, env)
+			if isError(evaluated) {
+				return []object.Object{evaluated}
+			}
+			result = append(result, evaluated)
+			continue
+		}
+
+		evaluated := Eval(spread.Value, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
+		}
+		elements, err := object.SpreadArguments(evaluated)
+		if err != nil {
+			return []object.Object{newError("%s", err)}
+		}
+		result = append(result, elements...)
+	}
+
+	return result
+}
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		err := object.CheckArity(
+			len(fn.Parameters),
+			len(fn.Defaults),
+			fn.Rest != nil,
+			len(args),
+		)
+		if err != nil {
+			return newError("%s", err)
+		}
+
+		extendedEnv, errObj := extendFunctionEnv(fn, args)
+		if errObj != nil {
+			return errObj
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		if result := fn.Fn(args...); result != nil {
+			return result
+		}
+		return NULL
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
+// Binds the parameters of fn to args, whose number CheckArity accepted.
+// Default values of missing arguments are evaluated in the new environment, so
+// they can refer to the parameters before them. Returns an error of a default
+// value, if any.
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
+) (*object.Environment, *object.Error) {
 	env := object.NewEnclosedEnvironment(fn.Env)
+	firstDefault := len(fn.Parameters) - len(fn.Defaults)
+
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+			continue
+		}
+
+		val := Eval(fn.Defaults[paramIdx-firstDefault], env)
+		if isError(val) {
+			return nil, val.(*object.Error)
+		}
+		env.Set(param.Value, val)
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
 	}
-	return env
+
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -403,13 +998,98 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == object.ERROR_OBJ && index.Type() == object.STRING_OBJ:
+		field, ok := left.(*object.Error).Field(index.(*object.String).Value)
+		if !ok {
+			return NULL
+		}
+		return field
+	case left.Type() == object.MODULE_OBJ && index.Type() == object.STRING_OBJ:
+		module := left.(*object.Module)
+		name := index.(*object.String).Value
+		export, ok := module.Field(name)
+		if !ok {
+			return newError("module %s has no export %s", module.Path, name)
+		}
+		return export
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
 }
 
+// Binds the variables of pattern to the parts of val. Returns nil, or an error
+// located at the pattern if val doesn't fit it.
+func evalDestructuring(
+	pattern ast.Pattern,
+	val object.Object,
+	env *object.Environment,
+) object.Object {
+	var values []object.Object
+	var err error
+
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		values, err = object.DestructureArray(
+			val,
+			len(pattern.Elements),
+			pattern.Rest != nil,
+		)
+	case *ast.HashPattern:
+		keys := []string{}
+		for _, pair := range pattern.Pairs {
+			keys = append(keys, pair.Key)
+		}
+		values, err = object.DestructureHash(val, keys)
+	}
+	if err != nil {
+		errObj := newError("%s", err)
+		errObj.Pos = pattern.Pos()
+		return errObj
+	}
+
+	for i, name := range pattern.Names() {
+		env.Set(name.Value, values[i])
+	}
+	return nil
+}
+
+// Loads the imported module, running it in a fresh environment the first time.
+// Errors of the module's program keep their position in the module.
+func evalImportExpression(
+	node *ast.ImportExpression,
+	env *object.Environment,
+) object.Object {
+	config := env.Config()
+	if config.Modules == nil {
+		config.Modules = module.NewLoader(nil)
+	}
+
+	module, err := config.Modules.Load(
+		node.Path,
+		node.Pos().Filename,
+		func(program *ast.Program) (func(string) (object.Object, bool), error) {
+			env := object.NewEnvironmentWithConfig(config)
+			result := Eval(program, env)
+			if isError(result) {
+				return nil, result.(*object.Error)
+			}
+			return env.Get, nil
+		},
+	)
+	if errObj, ok := err.(*object.Error); ok {
+		return errObj
+	}
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	return module
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
@@ -436,3 +1116,13 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	}
 	return arrayObject.Elements[idx]
 }
+
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	idx := index.(*object.Integer).Value
+
+	char, ok := str.(*object.String).RuneAt(idx)
+	if !ok {
+		return NULL
+	}
+	return char
+}
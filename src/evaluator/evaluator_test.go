@@ -2,9 +2,13 @@ package evaluator
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/tjapit/monkey/src/lexer"
+	"github.com/tjapit/monkey/src/module"
 	"github.com/tjapit/monkey/src/object"
 	"github.com/tjapit/monkey/src/parser"
 )
@@ -30,6 +34,10 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"Test 13", "3 * 3 * 3 + 10", 37},
 		{"Test 14", "3 * (3 * 3) + 10", 37},
 		{"Test 15", "(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"Test 16", "5 % 2", 1},
+		{"Test 17", "10 % 5", 0},
+		{"Test 18", "-7 % 3", -1},
+		{"Test 19", "2 + 7 % 4 * 2", 8},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -40,10 +48,14 @@ func TestEvalIntegerExpression(t *testing.T) {
 }
 
 func testEval(input string) object.Object {
+	return testEvalWithConfig(input, &object.EvalConfig{})
+}
+
+func testEvalWithConfig(input string, config *object.EvalConfig) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
-	env := object.NewEnvironment()
+	env := object.NewEnvironmentWithConfig(config)
 
 	return Eval(program, env)
 }
@@ -66,6 +78,47 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected float64
+	}{
+		{"Test 1", "3.14", 3.14},
+		{"Test 2", "-2.5", -2.5},
+		{"Test 3", "1.5 + 1.5", 3.0},
+		{"Test 4", "1 + 0.5", 1.5},
+		{"Test 5", "0.5 * 4", 2.0},
+		{"Test 6", "7 / 2.0", 3.5},
+		{"Test 7", "1e3 - 1", 999.0},
+		{"Test 8", "2.5e-1 * 2", 0.5},
+		{"Test 9", "5.5 % 2", 1.5},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			testFloatObject(t, testEval(tC.input), tC.expected)
+		})
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf(
+			"object has incorrect value. want=%g, got =%g",
+			expected,
+			result.Value,
+		)
+		return false
+	}
+
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	testCases := []struct {
 		desc     string
@@ -91,6 +144,21 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"Test 17", "(1 < 2) == false", false},
 		{"Test 18", "(1 > 2) == true", false},
 		{"Test 19", "(1 > 2) == false", true},
+		{"Test 20", "1 <= 1", true},
+		{"Test 21", "2 <= 1", false},
+		{"Test 22", "1 >= 1", true},
+		{"Test 23", "1 >= 2", false},
+		{"Test 24", "1.5 <= 2", true},
+		{"Test 25", "2 >= 2.5", false},
+		{"Test 26", "true && true", true},
+		{"Test 27", "true && false", false},
+		{"Test 28", "false || true", true},
+		{"Test 29", "false || false", false},
+		{"Test 30", "1 < 2 && 2 < 3", true},
+		{"Test 31", "1 > 2 || 2 > 3", false},
+		{"Test 32", "false && len(1)", false},
+		{"Test 33", "true || len(1)", true},
+		{"Test 34", "1 && \"monkey\"", true},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -290,6 +358,41 @@ func TestErrorHandling(t *testing.T) {
 			input:    `{"name": "Monkey"}[fn(x) { x }];`,
 			expected: "unusable as hash key: FUNCTION",
 		},
+		{
+			desc:     "Test 12",
+			input:    "x = 5;",
+			expected: "cannot assign to undeclared variable x",
+		},
+		{
+			desc:     "Test 13",
+			input:    "len = 5;",
+			expected: "cannot assign to builtin len",
+		},
+		{
+			desc:     "Test 14",
+			input:    "let arr = [1]; arr[1] = 5;",
+			expected: "index out of range: 1",
+		},
+		{
+			desc:     "Test 15",
+			input:    `let s = "abc"; s[0] = "x";`,
+			expected: "index assignment not supported: STRING",
+		},
+		{
+			desc:     "Test 16",
+			input:    `let x = 5; x += "a";`,
+			expected: "type mismatch: INTEGER + STRING",
+		},
+		{
+			desc:     "Test 17",
+			input:    "1 / 0",
+			expected: "division by zero",
+		},
+		{
+			desc:     "Test 18",
+			input:    "let x = 5; x %= 0;",
+			expected: "modulo by zero",
+		},
 	}
 
 	for _, tC := range testCases {
@@ -316,6 +419,116 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestCheckedArithmetic(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected interface{}
+	}{
+		{"Test 1", "9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"Test 2", "-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"Test 3", "4294967296 * 4294967296", "integer overflow: 4294967296 * 4294967296"},
+		{"Test 4", "let min = -9223372036854775807 - 1; min / -1", "integer overflow: -9223372036854775808 / -1"},
+		{"Test 5", "let min = -9223372036854775807 - 1; -min", "integer overflow: -(-9223372036854775808)"},
+		{"Test 6", "9223372036854775806 + 1", 9223372036854775807},
+		{"Test 7", "-4294967296 * 2147483648", -9223372036854775808},
+		{"Test 8", "try { 9223372036854775807 * 2 } catch (e) { e[\"kind\"] }", "RuntimeError"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEvalWithConfig(tC.input, &object.EvalConfig{CheckedArithmetic: true})
+
+			switch expected := tC.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				if str, ok := evaluated.(*object.String); ok {
+					if str.Value != expected {
+						t.Errorf("String has wrong value. want=%q, got =%q", expected, str.Value)
+					}
+					return
+				}
+
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				}
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. want=%q, got =%q", expected, errObj.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestBigIntArithmetic(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected interface{}
+	}{
+		{"Test 1", "9223372036854775807 + 1", "9223372036854775808"},
+		{"Test 2", "let min = -9223372036854775807 - 1; min - 1", "-9223372036854775809"},
+		{"Test 3", "4294967296 * 4294967296", "18446744073709551616"},
+		{"Test 4", "let min = -9223372036854775807 - 1; min / -1", "9223372036854775808"},
+		{"Test 5", "let min = -9223372036854775807 - 1; -min", "9223372036854775808"},
+		{"Test 6", "let min = -9223372036854775807 - 1; min % -1", 0},
+		{"Test 7", "100000000000000000000", "100000000000000000000"},
+		{"Test 8", "100000000000000000000 - 99999999999999999999", 1},
+		{"Test 9", "-9223372036854775808", -9223372036854775808},
+		{"Test 10", "18446744073709551616 / 4294967296", 4294967296},
+		{"Test 11", "-100000000000000000007 % 10", -7},
+		{"Test 12", "-100000000000000000007 / 10", "-10000000000000000000"},
+		{"Test 13", "100000000000000000000 > 9223372036854775807", true},
+		{"Test 14", "9223372036854775807 + 1 == 9223372036854775808", true},
+		{"Test 15", "100000000000000000000 != 100000000000000000000", false},
+		{"Test 16", "100000000000000000000 / 0", "division by zero"},
+		{"Test 17", "100000000000000000000 * 0.5", 5e19},
+		{"Test 18", "let x = 9223372036854775807; x += 1; x -= 1; x", 9223372036854775807},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval(tC.input)
+
+			switch expected := tC.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case float64:
+				testFloatObject(t, evaluated, expected)
+			case bool:
+				testBooleanObject(t, evaluated, expected)
+			case string:
+				if errObj, ok := evaluated.(*object.Error); ok {
+					if errObj.Message != expected {
+						t.Errorf("wrong error message. want=%q, got =%q", expected, errObj.Message)
+					}
+					return
+				}
+				testBigIntObject(t, evaluated, expected)
+			}
+		})
+	}
+}
+
+func testBigIntObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.BigInt)
+	if !ok {
+		t.Errorf("object is not BigInt. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value.String() != expected {
+		t.Errorf(
+			"object has incorrect value. want=%s, got =%s",
+			expected,
+			result.Value,
+		)
+		return false
+	}
+
+	return true
+}
+
 func TestLetStatements(t *testing.T) {
 	testCases := []struct {
 		desc     string
@@ -350,6 +563,155 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestAssignExpressions(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected int64
+	}{
+		{"Test 1", "let a = 5; a = 10; a;", 10},
+		{"Test 2", "let a = 5; a = a + 1;", 6},
+		{"Test 3", "let a = 5; a += 2; a;", 7},
+		{"Test 4", "let a = 5; a -= 2; a;", 3},
+		{"Test 5", "let a = 5; a *= 2; a;", 10},
+		{"Test 6", "let a = 5; a /= 2; a;", 2},
+		{"Test 7", "let a = 5; a %= 2; a;", 1},
+		{"Test 8", "let a = 1; let b = 2; a = b = 3; a + b;", 6},
+		{"Test 9", "let a = 1; let f = fn() { a = 5; }; f(); a;", 5},
+		{"Test 10", "let a = 1; let f = fn(a) { a = 5; }; f(2); a;", 1},
+		{"Test 11", "let c = fn() { let n = 0; fn() { n += 1 } }(); c(); c();", 2},
+		{"Test 12", "let arr = [1, 2, 3]; arr[1] = 5; arr[1];", 5},
+		{"Test 13", "let arr = [1, 2, 3]; arr[2] += 5; arr[2];", 8},
+		{"Test 14", `let h = {"a": 1}; h["b"] = 2; h["a"] + h["b"];`, 3},
+		{"Test 15", `let h = {"a": 1}; h["a"] *= 10; h["a"];`, 10},
+		{"Test 16", "let arr = [[1], [2]]; arr[1][0] = 7; arr[1][0];", 7},
+		{"Test 17", "let i = 0; let next = fn() { i += 1; i }; let arr = [0, 0]; arr[next()] += 3; arr[1] + i;", 4},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			testIntegerObject(t, testEval(tC.input), tC.expected)
+		})
+	}
+}
+
+func TestLoops(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected interface{}
+	}{
+		{"Test 1", "let i = 0; while (i < 5) { i += 1; } i;", 5},
+		{"Test 2", "let i = 0; while (true) { i += 1; if (i == 3) { break; } } i;", 3},
+		{"Test 3", "let i = 0; let s = 0; while (i < 5) { i += 1; if (i % 2 == 0) { continue; } s += i; } s;", 9},
+		{"Test 4", "let s = 0; for (x in [1, 2, 3]) { s += x; } s;", 6},
+		{"Test 5", "let s = 0; for (i, x in [10, 20]) { s += i * x; } s;", 20},
+		{"Test 6", `let s = ""; for (c in "abc") { s = c + s; } s;`, "cba"},
+		{"Test 7", `let s = 0; for (i, c in "héllo") { s += i; } s;`, 10},
+		{"Test 8", `let s = ""; for (k in {"b": 1, "a": 2}) { s += k; } s;`, "ab"},
+		{"Test 9", `let s = 0; for (k, v in {"b": 1, "a": 2}) { s += v; } s;`, 3},
+		{"Test 10", "let s = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { break; } s += x; } s;", 3},
+		{"Test 11", "let s = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { continue; } s += x; } s;", 7},
+		{"Test 12", "let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } 0 }; f();", 20},
+		{"Test 13", "let s = 0; for (x in [1, 2]) { for (y in [1, 2, 3]) { if (y == 2) { break; } s += x * y; } } s;", 3},
+		{"Test 14", "let i = 0; while (i < 100000) { i += 1; } i;", 100000},
+		{"Test 15", "for (x in []) { x; }", nil},
+		{"Test 16", "for (x in 5) { x; }", "cannot iterate over INTEGER"},
+		{"Test 17", "while (x) { 1; }", "identifier not found: x"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval(tC.input)
+
+			switch expected := tC.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case nil:
+				if evaluated != nil {
+					t.Errorf("expected no value. got=%T (%+v)", evaluated, evaluated)
+				}
+			case string:
+				if errObj, ok := evaluated.(*object.Error); ok {
+					if errObj.Message != expected {
+						t.Errorf("wrong error message. want=%q, got =%q", expected, errObj.Message)
+					}
+					return
+				}
+
+				str, ok := evaluated.(*object.String)
+				if !ok {
+					t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				}
+				if str.Value != expected {
+					t.Errorf("String has wrong value. want=%q, got =%q", expected, str.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestTryExpressions(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected interface{}
+	}{
+		{"Test 1", "try { 1 } catch (e) { 2 }", 1},
+		{"Test 2", `try { throw "bad"; 1 } catch (e) { e["message"] }`, "bad"},
+		{"Test 3", `try { throw "bad"; } catch (e) { e["kind"] }`, "Error"},
+		{"Test 4", `try { 1 + true } catch (e) { e["kind"] }`, "RuntimeError"},
+		{"Test 5", `try { throw error("nope", "ValueError"); } catch (e) { e["kind"] + ": " + e["message"] }`, "ValueError: nope"},
+		{"Test 6", `try { throw 42; } catch (e) { e["message"] }`, "42"},
+		{"Test 7", `let f = fn(x) { if (x > 1) { throw "big"; } x }; try { f(1) + f(2) } catch (e) { -1 }`, -1},
+		{"Test 8", "let s = 0; try { s += 1; } finally { s += 10; } s;", 11},
+		{"Test 9", `let s = 0; try { throw "x"; } catch (e) { s += 1; } finally { s += 10; } s;`, 11},
+		{"Test 10", "let s = 0; let f = fn() { try { return 1; } finally { s = 5; } }; f() + s;", 6},
+		{"Test 11", `let s = 0; try { try { throw "in"; } finally { s = 1; } } catch (e) { s }`, 1},
+		{"Test 12", `try { try { throw "a"; } catch (e) { throw e["message"] + "b"; } } catch (e) { e["message"] }`, "ab"},
+		{"Test 13", "let i = 0; let s = 0; while (i < 5) { i += 1; try { if (i == 2) { continue; } if (i == 4) { break; } s += i; } finally { s += 10; } } s;", 44},
+		{"Test 14", `try { throw "x"; } catch (e) { }`, nil},
+		{"Test 15", "try { 5 } finally { 6 }", 5},
+		{"Test 16", `let e = error("boom"); e["message"]`, "boom"},
+		{"Test 17", `let e = error("boom"); e["missing"]`, nil},
+		{"Test 18", `let r = try { throw "x"; } catch (e) { e }; r["message"]`, "x"},
+		{"Test 19", `let f = fn() { try { throw "x"; } catch (e) { return 1; } finally { 2 } }; f()`, 1},
+		{"Test 20", `throw "oops"; 5`, &object.Error{Message: "oops"}},
+		{"Test 21", `try { throw "a"; } finally { 1 }`, &object.Error{Message: "a"}},
+		{"Test 22", `try { 1 } finally { throw "f"; }`, &object.Error{Message: "f"}},
+		{"Test 23", `let f = fn() { throw "deep"; }; let g = fn() { f() + 1 }; g()`, &object.Error{Message: "deep"}},
+		{"Test 24", "error(1)", &object.Error{Message: "first argument to `error` must be STRING, got =INTEGER"}},
+		{"Test 25", `error("a", "b", "c")`, &object.Error{Message: "wrong number of arguments. want=1 or 2, got =3"}},
+		{"Test 26", "let r = try {\n  [1][\"a\"]\n} catch (e) { e };\nr[\"position\"];", "2:6"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval(tC.input)
+
+			switch expected := tC.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case nil:
+				testNullObject(t, evaluated)
+			case string:
+				str, ok := evaluated.(*object.String)
+				if !ok {
+					t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				}
+				if str.Value != expected {
+					t.Errorf("String has wrong value. want=%q, got =%q", expected, str.Value)
+				}
+			case *object.Error:
+				errObj, ok := evaluated.(*object.Error)
+				if !ok || !errObj.Thrown {
+					t.Fatalf("no thrown error returned. got=%T (%+v)", evaluated, evaluated)
+				}
+				if errObj.Message != expected.Message {
+					t.Errorf("wrong error message. want=%q, got =%q", expected.Message, errObj.Message)
+				}
+			}
+		})
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -418,6 +780,71 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameters(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected int64
+	}{
+		{"Test 1", "let f = fn(a, b = 10) { a + b }; f(1)", 11},
+		{"Test 2", "let f = fn(a, b = 10) { a + b }; f(1, 2)", 3},
+		{"Test 3", "let f = fn(a, b = a * 2, c = a + b) { a * 100 + b * 10 + c }; f(1)", 123},
+		{"Test 4", "let f = fn(a, b = a * 2, c = a + b) { a * 100 + b * 10 + c }; f(1, 5)", 156},
+		{"Test 5", "let f = fn(...xs) { len(xs) }; f()", 0},
+		{"Test 6", "let f = fn(...xs) { len(xs) }; f(1, 2, 3)", 3},
+		{"Test 7", "let f = fn(a, ...rest) { a + len(rest) * 10 + rest[1] }; f(1, 2, 3)", 24},
+		{"Test 8", "let add = fn(a, b, c) { a * 100 + b * 10 + c }; add(...[1, 2, 3])", 123},
+		{"Test 9", "let add = fn(a, b, c) { a * 100 + b * 10 + c }; add(1, ...[2], 3)", 123},
+		{"Test 10", "let add = fn(a, b, c) { a * 100 + b * 10 + c }; add(...[1], ...[2, 3])", 123},
+		{
+			"Test 11",
+			"let sum = fn(...xs) { let t = 0; for (x in xs) { t += x; } t }; sum(...[1, 2], 3, ...[], ...[4])",
+			10,
+		},
+		{"Test 12", `len(...["four"])`, 4},
+		{"Test 13", "let n = 5; let f = fn(a = n) { fn(b = a) { a + b } }; f()()", 10},
+		{"Test 14", "let f = fn(a, b = 1) { a + b }; let args = [1, 2]; f(...args) + f(...[4])", 8},
+		{
+			"Test 15",
+			"let fact = fn(n, acc = 1) { if (n == 0) { acc } else { fact(n - 1, acc * n) } }; fact(5)",
+			120,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			testIntegerObject(t, testEval(tC.input), tC.expected)
+		})
+	}
+}
+
+func TestArgumentErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "fn(a, b = 1) { a }()", "wrong number of arguments. want=1 to 2, got =0"},
+		{"Test 2", "fn(a, b = 1) { a }(1, 2, 3)", "wrong number of arguments. want=1 to 2, got =3"},
+		{"Test 3", "fn(a, ...r) { a }()", "wrong number of arguments. want at least 1, got =0"},
+		{"Test 4", "fn(a) { a }(1, 2)", "wrong number of arguments. want=1, got =2"},
+		{"Test 5", "len(...1)", "cannot spread INTEGER into arguments"},
+		{"Test 6", "fn(a) { a }(...[1, 2])", "wrong number of arguments. want=1, got =2"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval(tC.input)
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tC.expected {
+				t.Errorf("wrong error message. want=%q, got =%q", tC.expected, errObj.Message)
+			}
+		})
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 let newAdder = fn(x) {
@@ -469,6 +896,44 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringComparison(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected bool
+	}{
+		{"Test 1", `"monkey" == "monkey"`, true},
+		{"Test 2", `"monkey" != "monkey"`, false},
+		{"Test 3", `"monkey" == "banana"`, false},
+		{"Test 4", `"monkey" != "banana"`, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			testBooleanObject(t, testEval(tC.input), tC.expected)
+		})
+	}
+}
+
+func TestFloatComparison(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected bool
+	}{
+		{"Test 1", "1.5 < 2.5", true},
+		{"Test 2", "1.5 > 2", false},
+		{"Test 3", "1 == 1.0", true},
+		{"Test 4", "1.0 != 1", false},
+		{"Test 5", "0.1 + 0.2 == 0.3", false},
+		{"Test 6", "2 > 1.99", true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			testBooleanObject(t, testEval(tC.input), tC.expected)
+		})
+	}
+}
+
 func TestBuiltinFunction(t *testing.T) {
 	testCases := []struct {
 		desc     string
@@ -550,6 +1015,11 @@ func TestBuiltinFunction(t *testing.T) {
 			"first argument to `push` must be ARRAY, got =INTEGER",
 		},
 		{"Test 23", `push([], 1)`, []int{1}},
+		{
+			desc:     "Test 9",
+			input:    `len("héllo 🐒")`,
+			expected: 7,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -677,6 +1147,40 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestStringIndexExpressions(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected interface{}
+	}{
+		{"Test 1", `"monkey"[0]`, "m"},
+		{"Test 2", `"héllo"[1]`, "é"},
+		{"Test 3", `"🐒!"[1]`, "!"},
+		{"Test 4", `let s = "größe"; s[len(s) - 1]`, "e"},
+		{"Test 5", `"abc"[3]`, nil},
+		{"Test 6", `"abc"[-1]`, nil},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval(tC.input)
+
+			expected, ok := tC.expected.(string)
+			if !ok {
+				testNullObject(t, evaluated)
+				return
+			}
+
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. want=%q, got =%q", expected, str.Value)
+			}
+		})
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
   {
@@ -760,6 +1264,31 @@ func TestHashIndexExpressions(t *testing.T) {
 			input:    `{false: 5}[false]`,
 			expected: 5,
 		},
+		{
+			desc:     "Test 8",
+			input:    `{0.5: 5}[0.5]`,
+			expected: 5,
+		},
+		{
+			desc:     "Test 9",
+			input:    `{1: 5}[1.0]`,
+			expected: 5,
+		},
+		{
+			desc:     "Test 10",
+			input:    `{100000000000000000000: 5}[99999999999999999999 + 1]`,
+			expected: 5,
+		},
+		{
+			desc:     "Test 11",
+			input:    `{1: 5}[100000000000000000000 - 99999999999999999999]`,
+			expected: 5,
+		},
+		{
+			desc:     "Test 12",
+			input:    `{1e20: 5}[100000000000000000000]`,
+			expected: 5,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -773,3 +1302,265 @@ func TestHashIndexExpressions(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorPositions(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "5 + true;", "1:3"},
+		{"Test 2", "let a = 1;\n  foobar;", "2:3"},
+		{"Test 3", "let f = fn() {\n  -true\n};\nf();", "2:3"},
+		{"Test 4", `len(1)`, "1:4"},
+		{"Test 5", "let a = 1;\nthrow \"a\";", "2:1"},
+		{"Test 6", "let f = fn() {\n  throw error(\"f\");\n};\ntry { f() } catch (e) { throw e; }", "2:14"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval(tC.input)
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf(
+					"no error object returned. got=%T(%+v)",
+					evaluated,
+					evaluated,
+				)
+			}
+
+			if errObj.Pos.String() != tC.expected {
+				t.Errorf(
+					"wrong error position. want=%s, got =%s",
+					tC.expected,
+					errObj.Pos,
+				)
+			}
+		})
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected int64
+	}{
+		{"Test 1", "let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"Test 2", "let [a, ...rest] = [1, 2, 3]; len(rest) * 10 + rest[1]", 23},
+		{"Test 3", "let [a, ...rest] = [1]; len(rest)", 0},
+		{"Test 4", "let [...all] = [4, 5]; all[0] + all[1]", 9},
+		{"Test 5", `let {name, age} = {"age": 30, "name": "Ann", "x": 1}; len(name) + age`, 33},
+		{"Test 6", "let f = fn(pair) { let [p, q] = pair; p * q }; f([6, 7])", 42},
+		{"Test 7", "let g = fn(arr) { let [h, ...t] = arr; fn() { h + len(t) } }; g([10, 1, 1])()", 12},
+		{"Test 8", "let arr = [1, 2]; let [a, ...rest] = arr; rest[0] = 5; arr[1]", 2},
+		{"Test 9", "let [a, b] = [1, 2]; let [a, b] = [b, a]; a * 10 + b", 21},
+		{"Test 10", `let {x} = {"x": 1}; let [] = []; let {} = {}; x`, 1},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			testIntegerObject(t, testEval(tC.input), tC.expected)
+		})
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "let [a, b] = [1];", "wrong number of elements to destructure. want=2, got =1"},
+		{"Test 2", "let [a] = [1, 2];", "wrong number of elements to destructure. want=1, got =2"},
+		{"Test 3", "let [a, b, ...c] = [1];", "too few elements to destructure. want at least 2, got =1"},
+		{"Test 4", "let [a] = 1;", "cannot destructure INTEGER as an array"},
+		{"Test 5", `let {a} = {"b": 1};`, `missing key "a" in destructured hash`},
+		{"Test 6", "let {a} = [1];", "cannot destructure ARRAY as a hash"},
+		{"Test 7", `let {a} = {1: 1};`, `missing key "a" in destructured hash`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval("\n" + tC.input)
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tC.expected {
+				t.Errorf("wrong error message. want=%q, got =%q", tC.expected, errObj.Message)
+			}
+
+			// reported at the pattern
+			if errObj.Pos.String() != "2:5" {
+				t.Errorf("wrong error position. want=%q, got =%q", "2:5", errObj.Pos)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", `match (1) { 1 => "one", _ => "other" }`, "one"},
+		{"Test 2", `match (2) { 1 => "one", _ => "other" }`, "other"},
+		{"Test 3", `match (-1) { 1 => "one", -1 => "minus one" }`, "minus one"},
+		{"Test 4", `match (2.0) { 2 => "two" }`, "two"},
+		{"Test 5", `match ("hi") { "ho" => "no", "hi" => "yes" }`, "yes"},
+		{"Test 6", `match (false) { true => "t", false => "f" }`, "f"},
+		{"Test 7", `match ([1, 2]) { [] => "empty", [a] => "one", [a, b] => "two" }`, "two"},
+		{"Test 8", `match ([1, "x", 3]) { [1, s, ...rest] => s }`, "x"},
+		{"Test 9", `match ([[1, "in"]]) { [[2, s]] => "no", [[1, s]] => s }`, "in"},
+		{
+			"Test 10",
+			`match ({"kind": "circle", "r": "2"}) { {"kind": "rect"} => "rect", {"kind": "circle", r} => r }`,
+			"2",
+		},
+		{
+			"Test 11",
+			`match ({"name": "Ann", "age": 3}) { {name: "Bob"} => "bob", {name: n} => n }`,
+			"Ann",
+		},
+		{"Test 12", `match (5) { n if n < 3 => "small", n => "big" }`, "big"},
+		{"Test 13", `match ([3, 1]) { [a, b] if a < b => "asc", [a, b] => "desc" }`, "desc"},
+		{"Test 14", `let x = "outer"; match ("inner") { x => x }`, "inner"},
+		{"Test 15", `let f = fn(v) { match (v) { [h, ...t] => fn() { h } } }; f(["a", 1])()`, "a"},
+		{"Test 16", `match (1) { 1 => match ("a") { "b" => "x", _ => "y" }, _ => "z" }`, "y"},
+		{"Test 17", `match (1) { n if match (n) { 1 => false, _ => true } => "a", _ => "b" }`, "b"},
+		{"Test 18", `match (9223372036854775807 + 1) { 9223372036854775808 => "big" }`, "big"},
+		{"Test 19", `match (1) { {} => "hash", [] => "array", _ => "other" }`, "other"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval(tC.input)
+
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			}
+			if str.Value != tC.expected {
+				t.Errorf("String has wrong value. want=%q, got =%q", tC.expected, str.Value)
+			}
+		})
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", `match (3) { 1 => "one" }`, "no match arm for 3"},
+		{"Test 2", `match ([1]) { [a] if a > 1 => a }`, "no match arm for [1]"},
+		{"Test 3", `match ("s") {}`, "no match arm for s"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval("\n" + tC.input)
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tC.expected {
+				t.Errorf("wrong error message. want=%q, got =%q", tC.expected, errObj.Message)
+			}
+
+			// reported at the match expression
+			if errObj.Pos.String() != "2:1" {
+				t.Errorf("wrong error position. want=%q, got =%q", "2:1", errObj.Pos)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := writeTestModules(t)
+
+	testCases := []struct {
+		desc     string
+		input    string
+		expected int64
+	}{
+		{"Test 1", `import("lib.mk")["square"](4)`, 16},
+		{"Test 2", `let m = import("lib.mk"); m["inc"](); m["inc"]()`, 2},
+		{"Test 3", `let a = import("lib.mk"); let b = import("lib.mk"); a["inc"](); b["inc"]()`, 2},
+		{"Test 4", `import("lib.mk")["base"]`, 10},
+		{"Test 5", `let f = fn() { import("base.mk")["value"] }; f() + f()`, 20},
+		{"Test 6", `let x = 1; import("base.mk"); x`, 1},
+		{"Test 7", `let m = import("counter.mk"); m["inc"](); m["inc"](); m["count"]`, 2},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			config := &object.EvalConfig{Modules: module.NewLoader([]string{dir})}
+			testIntegerObject(t, testEvalWithConfig(tC.input, config), tC.expected)
+		})
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeTestModules(t)
+
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string // expected message prefix
+		line     int    // expected line of the error position
+	}{
+		{"Test 1", `import("lib.mk")["count"]`, "module " + dir + "/lib.mk has no export count", 1},
+		{"Test 2", `import("missing.mk")`, `cannot import "missing.mk": module not found`, 1},
+		{"Test 3", "\nimport(\"cycle.mk\")", "import cycle: ", 1},
+		{"Test 4", "\nimport(\"fail.mk\")", "division by zero", 2},
+		{"Test 5", "\nimport(\"bad.mk\")", `cannot import "bad.mk": parser errors:`, 2},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			config := &object.EvalConfig{Modules: module.NewLoader([]string{dir})}
+			evaluated := testEvalWithConfig(tC.input, config)
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if !strings.HasPrefix(errObj.Message, tC.expected) {
+				t.Errorf("wrong error message. want prefix %q, got =%q", tC.expected, errObj.Message)
+			}
+			if errObj.Pos.Line != tC.line {
+				t.Errorf("wrong error line. want=%d, got =%d", tC.line, errObj.Pos.Line)
+			}
+		})
+	}
+}
+
+// Modules imported by the import tests.
+var testModules = map[string]string{
+	"lib.mk": `
+let count = 0;
+export let square = fn(x) { x * x };
+export let inc = fn() { count += 1; count };
+export let base = import("base.mk")["value"];
+`,
+	"base.mk":    `export let value = 10;`,
+	"counter.mk": `export let count = 0; export let inc = fn() { count += 1; };`,
+	"cycle.mk":   `import("cycle.mk");`,
+	"fail.mk":    "let x = 1;\nx / 0;",
+	"bad.mk":     "let = 1;",
+}
+
+// Writes testModules to a temporary directory and returns it.
+func writeTestModules(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range testModules {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
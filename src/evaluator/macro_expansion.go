@@ -0,0 +1,130 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/tjapit/monkey/src/ast"
+	"github.com/tjapit/monkey/src/object"
+)
+
+// Moves the macros defined by top-level let statements out of the program and
+// into env, for ExpandMacros.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := program.Statements[:0]
+
+	for _, statement := range program.Statements {
+		letStatement, ok := statement.(*ast.LetStatement)
+		if !ok || letStatement.Pattern != nil {
+			statements = append(statements, statement)
+			continue
+		}
+
+		macroLiteral, ok := letStatement.Value.(*ast.MacroLiteral)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+
+		env.Set(letStatement.Name.Value, &object.Macro{
+			Parameters: macroLiteral.Parameters,
+			Body:       macroLiteral.Body,
+			Env:        env,
+		})
+	}
+
+	program.Statements = statements
+}
+
+// Returns a copy of program in which each call of a macro from env is replaced
+// by the code the macro returns for it. The macros get their arguments as
+// quoted code, unevaluated.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, error) {
+	var err error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		if err != nil {
+			return node
+		}
+
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+
+		macro, ok := macroOf(call, env)
+		if !ok {
+			return node
+		}
+
+		name := call.Function.String()
+		if len(call.Arguments) != len(macro.Parameters) {
+			err = fmt.Errorf(
+				"%s: wrong number of arguments to macro %s. want=%d, got =%d",
+				call.Pos(),
+				name,
+				len(macro.Parameters),
+				len(call.Arguments),
+			)
+			return node
+		}
+
+		evaluated := unwrapReturnValue(Eval(macro.Body, extendMacroEnv(macro, call)))
+		if isError(evaluated) {
+			errObj := evaluated.(*object.Error)
+			err = fmt.Errorf("%s: in macro %s: %s", errObj.Pos, name, errObj.Message)
+			return node
+		}
+
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			err = fmt.Errorf(
+				"%s: macro %s must return a QUOTE, got %s",
+				call.Pos(),
+				name,
+				typeOf(evaluated),
+			)
+			return node
+		}
+
+		return quote.Node
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+func macroOf(call *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(ident.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
+
+func extendMacroEnv(
+	macro *object.Macro,
+	call *ast.CallExpression,
+) *object.Environment {
+	env := object.NewEnclosedEnvironment(macro.Env)
+	for paramIdx, param := range macro.Parameters {
+		env.Set(param.Value, &object.Quote{Node: call.Arguments[paramIdx]})
+	}
+	return env
+}
+
+// Names the type of a macro's result, which may be nothing at all.
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
@@ -0,0 +1,170 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/tjapit/monkey/src/ast"
+	"github.com/tjapit/monkey/src/lexer"
+	"github.com/tjapit/monkey/src/object"
+	"github.com/tjapit/monkey/src/parser"
+)
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	`
+
+	env := object.NewEnvironment()
+	program := testParseProgram(input)
+
+	DefineMacros(program, env)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	if _, ok := env.Get("number"); ok {
+		t.Fatalf("number should not be defined")
+	}
+	if _, ok := env.Get("function"); ok {
+		t.Fatalf("function should not be defined")
+	}
+
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in environment.")
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+	if macro.Parameters[0].String() != "x" || macro.Parameters[1].String() != "y" {
+		t.Fatalf("wrong parameters. got=%v", macro.Parameters)
+	}
+
+	expectedBody := "(x + y)"
+	if macro.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, macro.Body.String())
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{
+			"Test 1",
+			`
+			let infixExpression = macro() { quote(1 + 2); };
+
+			infixExpression();
+			`,
+			`(1 + 2)`,
+		},
+		{
+			"Test 2",
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+			reverse(2 + 2, 10 - 5);
+			`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			"Test 3",
+			`
+			let unless = macro(condition, consequence, alternative) {
+				quote(if (!(unquote(condition))) {
+					unquote(consequence);
+				} else {
+					unquote(alternative);
+				});
+			};
+
+			unless(10 > 5, puts("not greater"), puts("greater"));
+			`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+		{
+			"Test 4",
+			`
+			let double = macro(x) { quote(unquote(x) * 2); };
+
+			let f = fn(y) { double(y + 1) };
+			`,
+			`let f = fn(y) { (y + 1) * 2 };`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			expected := testParseProgram(tC.expected)
+			program := testParseProgram(tC.input)
+
+			env := object.NewEnvironment()
+			DefineMacros(program, env)
+			expanded, err := ExpandMacros(program, env)
+			if err != nil {
+				t.Fatalf("macro expansion failed: %s", err)
+			}
+
+			if expanded.String() != expected.String() {
+				t.Errorf("not equal. want=%q, got =%q", expected.String(), expanded.String())
+			}
+		})
+	}
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{
+			"Test 1",
+			"let m = macro(a) { quote(unquote(a)) };\nm(1, 2);",
+			"2:2: wrong number of arguments to macro m. want=1, got =2",
+		},
+		{
+			"Test 2",
+			"let m = macro(a) { 1 };\nm(1);",
+			"2:2: macro m must return a QUOTE, got INTEGER",
+		},
+		{
+			"Test 3",
+			"let m = macro(a) {\n  throw \"no\";\n};\nm(1);",
+			"2:3: in macro m: no",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			program := testParseProgram(tC.input)
+
+			env := object.NewEnvironment()
+			DefineMacros(program, env)
+			_, err := ExpandMacros(program, env)
+			if err == nil {
+				t.Fatalf("expected macro expansion error, got none")
+			}
+
+			if err.Error() != tC.expected {
+				t.Errorf("wrong error. want=%q, got =%q", tC.expected, err.Error())
+			}
+		})
+	}
+}
+
+func testParseProgram(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
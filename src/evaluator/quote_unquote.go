@@ -0,0 +1,37 @@
+package evaluator
+
+import (
+	"github.com/tjapit/monkey/src/ast"
+	"github.com/tjapit/monkey/src/object"
+)
+
+// Returns node as code rather than evaluating it, except for the unquote(...)
+// calls in it, which are replaced by the code of their values.
+func quote(node ast.Node, env *object.Environment) object.Object {
+	var err object.Object
+
+	node = ast.Modify(node, func(node ast.Node) ast.Node {
+		if err != nil || !object.IsUnquoteCall(node) {
+			return node
+		}
+
+		call := node.(*ast.CallExpression)
+		unquoted := Eval(call.Arguments[0], env)
+		if isError(unquoted) {
+			err = unquoted
+			return node
+		}
+
+		converted, convErr := object.ToNode(unquoted)
+		if convErr != nil {
+			err = newError("%s", convErr)
+			return node
+		}
+		return converted
+	})
+
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
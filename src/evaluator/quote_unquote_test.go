@@ -0,0 +1,97 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/tjapit/monkey/src/object"
+)
+
+func TestQuote(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", `quote(5)`, `5`},
+		{"Test 2", `quote(5 + 8)`, `(5 + 8)`},
+		{"Test 3", `quote(foobar)`, `foobar`},
+		{"Test 4", `quote(foobar + barfoo)`, `(foobar + barfoo)`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			testQuoteObject(t, testEval(tC.input), tC.expected)
+		})
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", `quote(unquote(4))`, `4`},
+		{"Test 2", `quote(unquote(4 + 4))`, `8`},
+		{"Test 3", `quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{"Test 4", `quote(unquote(4 + 4) + 8)`, `(8 + 8)`},
+		{"Test 5", `let foobar = 8; quote(foobar)`, `foobar`},
+		{"Test 6", `let foobar = 8; quote(unquote(foobar))`, `8`},
+		{"Test 7", `quote(unquote(true))`, `true`},
+		{"Test 8", `quote(unquote(true == false))`, `false`},
+		{"Test 9", `quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{"Test 10", `let q = quote(4 + 4); quote(unquote(4 + 4) + unquote(q))`, `(8 + (4 + 4))`},
+		{"Test 11", `quote(unquote(1.5) * unquote("s"))`, `(1.5 * s)`},
+		{"Test 12", `quote(unquote(100000000000000000000))`, `100000000000000000000`},
+		{"Test 13", `let f = fn(x) { quote(unquote(x) + 1) }; f(1); f(2)`, `(2 + 1)`},
+		{"Test 14", `quote(unquote(...[1]))`, `unquote(...[1])`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			testQuoteObject(t, testEval(tC.input), tC.expected)
+		})
+	}
+}
+
+func TestQuoteErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", `quote(unquote([1]))`, "cannot unquote ARRAY"},
+		{"Test 2", `quote(unquote(x))`, "identifier not found: x"},
+		{"Test 3", `let f = fn() { macro(x) { x } }; f()`, "macros must be defined by top-level let statements"},
+		{"Test 4", `quote(...[1])`, "identifier not found: quote"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			evaluated := testEval(tC.input)
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tC.expected {
+				t.Errorf("wrong error message. want=%q, got =%q", tC.expected, errObj.Message)
+			}
+		})
+	}
+}
+
+func testQuoteObject(t *testing.T, obj object.Object, expected string) bool {
+	quote, ok := obj.(*object.Quote)
+	if !ok {
+		t.Errorf("object is not Quote. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if quote.Node == nil {
+		t.Errorf("quote.Node is nil")
+		return false
+	}
+	if quote.Node.String() != expected {
+		t.Errorf("not equal. want=%q, got =%q", expected, quote.Node.String())
+		return false
+	}
+
+	return true
+}
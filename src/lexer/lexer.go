@@ -1,32 +1,61 @@
 package lexer
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/tjapit/monkey/src/token"
 )
 
 type Lexer struct {
 	input        string
-	position     int  // current position in input (points to current char)
-	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	position     int  // current byte offset in input (points to current char)
+	readPosition int  // current reading byte offset in input (after current char)
+	ch           rune // current char under examination
+
+	filename string
+	line     int // line of the current char
+	column   int // column of the current char
+
+	preserveComments bool // emit COMMENT tokens instead of skipping comments
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewWithFilename("", input)
+}
+
+// Creates a Lexer whose token positions refer to the given filename.
+func NewWithFilename(filename string, input string) *Lexer {
+	l := &Lexer{input: input, filename: filename, line: 1}
 	l.readChar()
 	return l
 }
 
+// Makes the lexer emit comments as COMMENT tokens, e.g. for tools that need to
+// reattach them to the AST. Comments are skipped by default.
+func (l *Lexer) PreserveComments() {
+	l.preserveComments = true
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition++
+	l.readPosition += width
 }
 
 func (l *Lexer) NextToken() token.Token {
@@ -34,6 +63,26 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.skipWhitespace()
 
+	pos := l.pos()
+
+	for l.isCommentStart() {
+		comment, ok := l.readComment()
+		if !ok {
+			return token.Token{
+				Type:    token.ILLEGAL,
+				Literal: "unterminated block comment",
+				Pos:     pos,
+			}
+		}
+
+		if l.preserveComments {
+			return token.Token{Type: token.COMMENT, Literal: comment, Pos: pos}
+		}
+
+		l.skipWhitespace()
+		pos = l.pos()
+	}
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -43,16 +92,42 @@ func (l *Lexer) NextToken() token.Token {
 				Type:    token.EQ,
 				Literal: string(ch) + string(l.ch),
 			}
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.ARROW,
+				Literal: string(ch) + string(l.ch),
+			}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok = l.readString()
+	case '`':
+		tok = l.readRawString()
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.PLUS_ASSIGN,
+				Literal: string(ch) + string(l.ch),
+			}
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.MINUS_ASSIGN,
+				Literal: string(ch) + string(l.ch),
+			}
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -65,19 +140,96 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.SLASH_ASSIGN,
+				Literal: string(ch) + string(l.ch),
+			}
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.ASTERISK_ASSIGN,
+				Literal: string(ch) + string(l.ch),
+			}
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.PERCENT_ASSIGN,
+				Literal: string(ch) + string(l.ch),
+			}
+		} else {
+			tok = newToken(token.PERCENT, l.ch)
+		}
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.LT_EQ,
+				Literal: string(ch) + string(l.ch),
+			}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.GT_EQ,
+				Literal: string(ch) + string(l.ch),
+			}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.AND,
+				Literal: string(ch) + string(l.ch),
+			}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{
+				Type:    token.OR,
+				Literal: string(ch) + string(l.ch),
+			}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case '.':
+		if l.isEllipsis() {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -97,10 +249,11 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos = pos
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -108,28 +261,151 @@ func (l *Lexer) NextToken() token.Token {
 	}
 
 	l.readChar()
+	tok.Pos = pos
 	return tok
 }
 
-func (l *Lexer) readString() string {
+// Position of the current char.
+func (l *Lexer) pos() token.Position {
+	return token.Position{Filename: l.filename, Line: l.line, Column: l.column}
+}
+
+// Reads a double-quoted string, resolving escape sequences. Malformed or
+// unterminated strings result in an ILLEGAL token describing the problem.
+// Leaves l.ch on the closing quote.
+func (l *Lexer) readString() token.Token {
+	var out strings.Builder
+	errMsg := ""
+
+	for {
+		l.readChar()
+
+		switch l.ch {
+		case 0:
+			return token.Token{
+				Type:    token.ILLEGAL,
+				Literal: "unterminated string literal",
+			}
+		case '"':
+			if errMsg != "" {
+				return token.Token{Type: token.ILLEGAL, Literal: errMsg}
+			}
+			return token.Token{Type: token.STRING, Literal: out.String()}
+		case '\\':
+			l.readChar()
+			if l.ch == 0 {
+				continue // reported as unterminated
+			}
+
+			r, msg := l.readEscape()
+			if msg != "" && errMsg == "" {
+				errMsg = msg // keep scanning to the closing quote
+			}
+			out.WriteRune(r)
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// Resolves the escape sequence whose first char after the backslash is l.ch.
+// Returns an error message if the sequence is invalid.
+func (l *Lexer) readEscape() (rune, string) {
+	switch l.ch {
+	case 'n':
+		return '\n', ""
+	case 't':
+		return '\t', ""
+	case '"':
+		return '"', ""
+	case '\\':
+		return '\\', ""
+	case 'u':
+		return l.readUnicodeEscape()
+	default:
+		return utf8.RuneError, fmt.Sprintf("invalid escape sequence \"\\%c\"", l.ch)
+	}
+}
+
+// Reads a `\u{...}` escape of 1 to 6 hex digits, l.ch is the `u`.
+func (l *Lexer) readUnicodeEscape() (rune, string) {
+	if l.peekChar() != '{' {
+		return utf8.RuneError, "invalid unicode escape, expected \"\\u{...}\""
+	}
+	l.readChar()
+
+	var digits strings.Builder
+	for l.peekChar() != '}' && l.peekChar() != '"' && l.peekChar() != 0 {
+		l.readChar()
+		digits.WriteRune(l.ch)
+	}
+
+	if l.peekChar() != '}' {
+		return utf8.RuneError, "unterminated unicode escape"
+	}
+	l.readChar()
+
+	hex := digits.String()
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) > 6 || !utf8.ValidRune(rune(value)) {
+		return utf8.RuneError, fmt.Sprintf("invalid unicode escape \"\\u{%s}\"", hex)
+	}
+
+	return rune(value), ""
+}
+
+// Reads a backtick-quoted string verbatim, it may span multiple lines and
+// has no escape sequences. Leaves l.ch on the closing backtick.
+func (l *Lexer) readRawString() token.Token {
 	position := l.position + 1
+
 	for {
 		l.readChar()
-		if l.ch == '\\' {
-			l.readChar() // account for escape sequences
-			continue
+
+		switch l.ch {
+		case 0:
+			return token.Token{
+				Type:    token.ILLEGAL,
+				Literal: "unterminated raw string literal",
+			}
+		case '`':
+			return token.Token{
+				Type:    token.STRING,
+				Literal: l.input[position:l.position],
+			}
 		}
-		if l.ch == '"' || l.ch == 0 {
-			break
+	}
+}
+
+func (l *Lexer) isCommentStart() bool {
+	return l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*')
+}
+
+// Reads a `//` line comment or `/* */` block comment including its
+// delimiters. Leaves l.ch on the first char after the comment. Returns false
+// for an unterminated block comment.
+func (l *Lexer) readComment() (string, bool) {
+	position := l.position
+	l.readChar()
+
+	if l.ch == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
 		}
+		return l.input[position:l.position], true
 	}
-	result := l.input[position:l.position]
 
-	result = strings.ReplaceAll(result, "\\n", "\n")
-	result = strings.ReplaceAll(result, "\\t", "\t")
-	result = strings.ReplaceAll(result, "\\\"", "\"")
+	l.readChar() // skip the '*'
+	for !(l.ch == '*' && l.peekChar() == '/') {
+		if l.ch == 0 {
+			return "", false
+		}
+		l.readChar()
+	}
+	l.readChar()
+	l.readChar()
 
-	return result
+	return l.input[position:l.position], true
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -138,12 +414,12 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -154,22 +430,68 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func isLetter(ch byte) bool {
-	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func (l *Lexer) readNumber() string {
+// Reads an integer or a float with an optional fraction and exponent, e.g.
+// `42`, `3.14`, `1e-9`. Returns the literal and INT or FLOAT.
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
+	tokenType := token.TokenType(token.INT)
+
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if l.isExponentStart() {
+		tokenType = token.FLOAT
+		l.readChar() // 'e' or 'E'
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+
+	return l.input[position:l.position], tokenType
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
 
-func isDigit(ch byte) bool {
+// Reports whether l.ch starts an exponent, i.e. `e` or `E` followed by digits
+// with an optional sign.
+func (l *Lexer) isExponentStart() bool {
+	if l.ch != 'e' && l.ch != 'E' {
+		return false
+	}
+
+	next := l.peekChar()
+	if next == '+' || next == '-' {
+		after := l.readPosition + 1
+		return after < len(l.input) && isDigit(rune(l.input[after]))
+	}
+
+	return isDigit(next)
+}
+
+// Reports whether l.ch starts `...`.
+func (l *Lexer) isEllipsis() bool {
+	after := l.readPosition + 1
+	return l.peekChar() == '.' && after < len(l.input) && l.input[after] == '.'
+}
+
+func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
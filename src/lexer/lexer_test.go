@@ -49,6 +49,250 @@ func TestNextTokenSymbols(t *testing.T) {
 	}
 }
 
+func TestNextTokenLoopKeywords(t *testing.T) {
+	input := `while for in break continue inside`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.IDENT, "inside"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(
+				"tests[%d] - token wrong. expected=%s %q, got =%s %q",
+				i,
+				tt.expectedType,
+				tt.expectedLiteral,
+				tok.Type,
+				tok.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenErrorKeywords(t *testing.T) {
+	input := `try catch finally throw trying`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.TRY, "try"},
+		{token.CATCH, "catch"},
+		{token.FINALLY, "finally"},
+		{token.THROW, "throw"},
+		{token.IDENT, "trying"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(
+				"tests[%d] - token wrong. expected=%s %q, got =%s %q",
+				i,
+				tt.expectedType,
+				tt.expectedLiteral,
+				tok.Type,
+				tok.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenModuleKeywords(t *testing.T) {
+	input := `export let m = import("lib.mk");`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.EXPORT, "export"},
+		{token.LET, "let"},
+		{token.IDENT, "m"},
+		{token.ASSIGN, "="},
+		{token.IMPORT, "import"},
+		{token.LPAREN, "("},
+		{token.STRING, "lib.mk"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(
+				"tests[%d] - token wrong. expected=%s %q, got =%s %q",
+				i,
+				tt.expectedType,
+				tt.expectedLiteral,
+				tok.Type,
+				tok.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenEllipsis(t *testing.T) {
+	input := `[a, ...b] .. .5`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "b"},
+		{token.RBRACKET, "]"},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.INT, "5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(
+				"tests[%d] - token wrong. expected=%s %q, got =%s %q",
+				i,
+				tt.expectedType,
+				tt.expectedLiteral,
+				tok.Type,
+				tok.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenMatch(t *testing.T) {
+	input := `match (x) { 1 => a, _ if b == c => d }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.INT, "1"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.IDENT, "_"},
+		{token.IF, "if"},
+		{token.IDENT, "b"},
+		{token.EQ, "=="},
+		{token.IDENT, "c"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "d"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(
+				"tests[%d] - token wrong. expected=%s %q, got =%s %q",
+				i,
+				tt.expectedType,
+				tt.expectedLiteral,
+				tok.Type,
+				tok.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenOperators(t *testing.T) {
+	input := `a <= b >= c % d && e || f < g > h & | += -= *= /= %=`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "d"},
+		{token.AND, "&&"},
+		{token.IDENT, "e"},
+		{token.OR, "||"},
+		{token.IDENT, "f"},
+		{token.LT, "<"},
+		{token.IDENT, "g"},
+		{token.GT, ">"},
+		{token.IDENT, "h"},
+		{token.ILLEGAL, "&"},
+		{token.ILLEGAL, "|"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.MINUS_ASSIGN, "-="},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.SLASH_ASSIGN, "/="},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf(
+				"tests[%d] - tokentype wrong. expected=%q, got =%q",
+				i,
+				tt.expectedType,
+				tok.Type,
+			)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf(
+				"tests[%d] - literal wrong. expected=%q, got =%q",
+				i,
+				tt.expectedLiteral,
+				tok.Literal,
+			)
+		}
+	}
+}
+
 func TestNextToken(t *testing.T) {
 	input := `let five = 5;
 let ten = 10;
@@ -58,7 +302,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -221,3 +465,328 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestNextTokenPositions(t *testing.T) {
+	input := `let five = 5;
+  five + "ten"
+`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 10},
+		{token.INT, 1, 12},
+		{token.SEMICOLON, 1, 13},
+		{token.IDENT, 2, 3},
+		{token.PLUS, 2, 8},
+		{token.STRING, 2, 10},
+		{token.EOF, 3, 1},
+	}
+
+	l := NewWithFilename("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf(
+				"tests[%d] - tokentype wrong. expected=%q, got =%q",
+				i,
+				tt.expectedType,
+				tok.Type,
+			)
+		}
+
+		if tok.Pos.Filename != "test.mk" {
+			t.Fatalf(
+				"tests[%d] - filename wrong. expected=%q, got =%q",
+				i,
+				"test.mk",
+				tok.Pos.Filename,
+			)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf(
+				"tests[%d] - position wrong. expected=%d:%d, got =%d:%d",
+				i,
+				tt.expectedLine,
+				tt.expectedColumn,
+				tok.Pos.Line,
+				tok.Pos.Column,
+			)
+		}
+	}
+}
+
+func TestNextTokenUnicode(t *testing.T) {
+	input := `let größe = "héllo 🐒"; größe + π`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "größe", 5},
+		{token.ASSIGN, "=", 11},
+		{token.STRING, "héllo 🐒", 13},
+		{token.SEMICOLON, ";", 22},
+		{token.IDENT, "größe", 24},
+		{token.PLUS, "+", 30},
+		{token.IDENT, "π", 32},
+		{token.EOF, "", 33},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf(
+				"tests[%d] - tokentype wrong. expected=%q, got =%q",
+				i,
+				tt.expectedType,
+				tok.Type,
+			)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf(
+				"tests[%d] - literal wrong. expected=%q, got =%q",
+				i,
+				tt.expectedLiteral,
+				tok.Literal,
+			)
+		}
+
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf(
+				"tests[%d] - column wrong. expected=%d, got =%d",
+				i,
+				tt.expectedColumn,
+				tok.Pos.Column,
+			)
+		}
+	}
+}
+
+func TestNextTokenStrings(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"Newline escape", `"a\nb"`, token.STRING, "a\nb"},
+		{"Tab escape", `"a\tb"`, token.STRING, "a\tb"},
+		{"Quote escape", `"say \"hi\""`, token.STRING, `say "hi"`},
+		{"Backslash escape", `"C:\\monkey"`, token.STRING, `C:\monkey`},
+		{"Unicode escape", `"\u{1F412} \u{e9}"`, token.STRING, "🐒 é"},
+		{"Raw string", "`a\\n\"b\"`", token.STRING, `a\n"b"`},
+		{"Multiline raw string", "`{\n  \"k\": 1\n}`", token.STRING, "{\n  \"k\": 1\n}"},
+		{"Invalid escape", `"a\qb"`, token.ILLEGAL, `invalid escape sequence "\q"`},
+		{"Invalid unicode escape", `"\u{zz}"`, token.ILLEGAL, `invalid unicode escape "\u{zz}"`},
+		{"Out of range unicode escape", `"\u{110000}"`, token.ILLEGAL, `invalid unicode escape "\u{110000}"`},
+		{"Unterminated unicode escape", `"\u{41"`, token.ILLEGAL, "unterminated unicode escape"},
+		{"Unterminated string", `"abc`, token.ILLEGAL, "unterminated string literal"},
+		{"Unterminated escape", `"abc\`, token.ILLEGAL, "unterminated string literal"},
+		{"Unterminated raw string", "`abc", token.ILLEGAL, "unterminated raw string literal"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := New(tC.input)
+			tok := l.NextToken()
+
+			if tok.Type != tC.expectedType {
+				t.Fatalf(
+					"tokentype wrong. expected=%q, got =%q (%q)",
+					tC.expectedType,
+					tok.Type,
+					tok.Literal,
+				)
+			}
+
+			if tok.Literal != tC.expectedLiteral {
+				t.Fatalf(
+					"literal wrong. expected=%q, got =%q",
+					tC.expectedLiteral,
+					tok.Literal,
+				)
+			}
+
+			if next := l.NextToken(); next.Type != token.EOF {
+				t.Fatalf("expected EOF after string, got =%q", next.Type)
+			}
+		})
+	}
+}
+
+func TestRawStringPositions(t *testing.T) {
+	input := "`a\nb` x"
+
+	l := New(input)
+	l.NextToken()
+	tok := l.NextToken()
+
+	if tok.Pos.Line != 2 || tok.Pos.Column != 4 {
+		t.Fatalf("position wrong. expected=2:4, got =%s", tok.Pos)
+	}
+}
+
+func TestNextTokenComments(t *testing.T) {
+	input := `// leading comment
+let x = 10 / 2; // trailing comment
+/* block
+   comment */ x /* inline */ * 2
+//`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.ASTERISK, "*"},
+		{token.INT, "2"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf(
+				"tests[%d] - tokentype wrong. expected=%q, got =%q",
+				i,
+				tt.expectedType,
+				tok.Type,
+			)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf(
+				"tests[%d] - literal wrong. expected=%q, got =%q",
+				i,
+				tt.expectedLiteral,
+				tok.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenPreservedComments(t *testing.T) {
+	input := "// doc\nlet x = 1; /* a\nb */"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{token.COMMENT, "// doc", 1},
+		{token.LET, "let", 2},
+		{token.IDENT, "x", 2},
+		{token.ASSIGN, "=", 2},
+		{token.INT, "1", 2},
+		{token.SEMICOLON, ";", 2},
+		{token.COMMENT, "/* a\nb */", 2},
+		{token.EOF, "", 3},
+	}
+
+	l := New(input)
+	l.PreserveComments()
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(
+				"tests[%d] - token wrong. expected=%q %q, got =%q %q",
+				i,
+				tt.expectedType,
+				tt.expectedLiteral,
+				tok.Type,
+				tok.Literal,
+			)
+		}
+
+		if tok.Pos.Line != tt.expectedLine {
+			t.Fatalf(
+				"tests[%d] - line wrong. expected=%d, got =%d",
+				i,
+				tt.expectedLine,
+				tok.Pos.Line,
+			)
+		}
+	}
+}
+
+func TestNextTokenUnterminatedComment(t *testing.T) {
+	l := New("1 /* never closed")
+	l.NextToken()
+
+	tok := l.NextToken()
+	if tok.Type != token.ILLEGAL || tok.Literal != "unterminated block comment" {
+		t.Fatalf("wrong token. got =%q %q", tok.Type, tok.Literal)
+	}
+	if tok.Pos.Column != 3 {
+		t.Fatalf("column wrong. expected=%d, got =%d", 3, tok.Pos.Column)
+	}
+}
+
+func TestNextTokenNumbers(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected []token.Token
+	}{
+		{"Integer", "42", []token.Token{{Type: token.INT, Literal: "42"}}},
+		{"Fraction", "3.14", []token.Token{{Type: token.FLOAT, Literal: "3.14"}}},
+		{"Negative exponent", "1e-9", []token.Token{{Type: token.FLOAT, Literal: "1e-9"}}},
+		{"Signed exponent", "2.5E+3", []token.Token{{Type: token.FLOAT, Literal: "2.5E+3"}}},
+		{"Trailing dot", "1.", []token.Token{
+			{Type: token.INT, Literal: "1"},
+			{Type: token.ILLEGAL, Literal: "."},
+		}},
+		{"Exponent without digits", "1e", []token.Token{
+			{Type: token.INT, Literal: "1"},
+			{Type: token.IDENT, Literal: "e"},
+		}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := New(tC.input)
+
+			for i, expected := range tC.expected {
+				tok := l.NextToken()
+				if tok.Type != expected.Type || tok.Literal != expected.Literal {
+					t.Fatalf(
+						"token[%d] wrong. expected=%s %q, got =%s %q",
+						i,
+						expected.Type,
+						expected.Literal,
+						tok.Type,
+						tok.Literal,
+					)
+				}
+			}
+
+			if next := l.NextToken(); next.Type != token.EOF {
+				t.Fatalf("expected EOF after number, got =%q", next.Type)
+			}
+		})
+	}
+}
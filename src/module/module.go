@@ -0,0 +1,164 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tjapit/monkey/src/ast"
+	"github.com/tjapit/monkey/src/lexer"
+	"github.com/tjapit/monkey/src/object"
+	"github.com/tjapit/monkey/src/parser"
+)
+
+// Executes the program of a module. Returns a function that looks up the
+// current value of the module's top-level bindings once it ran.
+type Runner = func(program *ast.Program) (lookup func(name string) (object.Object, bool), err error)
+
+// Finds, runs and caches the modules imported by a program. Each module is
+// run at most once per Loader, later imports of the same file get the cached
+// Module. A Loader holds values of a single engine, so engines can't share one.
+type Loader struct {
+	// Directories searched for imported paths after the directory of the
+	// importing file.
+	SearchPath []string
+
+	// Rewrites a module's program before it's run, e.g. to expand its macros.
+	// Optional.
+	Expand func(program *ast.Program) (*ast.Program, error)
+
+	modules map[string]*object.Module // loaded modules by absolute path
+	loading []string                  // files being loaded, importers first
+}
+
+func NewLoader(searchPath []string) *Loader {
+	return &Loader{
+		SearchPath: searchPath,
+		modules:    map[string]*object.Module{},
+	}
+}
+
+// Loads the module path imported by the file from, running it with run unless
+// it's cached. Errors of the module's program are returned as they are, e.g. a
+// runtime *object.Error located in the module.
+func (l *Loader) Load(path, from string, run Runner) (*object.Module, error) {
+	filename, err := l.resolve(path, from)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	if module, ok := l.modules[key]; ok {
+		return module, nil
+	}
+
+	for i, loading := range l.loading {
+		if abs, _ := filepath.Abs(loading); abs == key {
+			return nil, fmt.Errorf(
+				"import cycle: %s -> %s",
+				strings.Join(l.loading[i:], " -> "),
+				filename,
+			)
+		}
+	}
+	defer l.Enter(filename)()
+
+	program, err := l.parse(path, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup, err := run(program)
+	if err != nil {
+		return nil, err
+	}
+
+	module := &object.Module{
+		Path:    filename,
+		Exports: ExportedNames(program),
+		Lookup:  lookup,
+	}
+
+	l.modules[key] = module
+	return module, nil
+}
+
+// Marks the file as loading until the returned function is called. Programs
+// run without Load, like the entry file of a run, call it so that importing
+// them back is reported as an import cycle instead of running them again.
+func (l *Loader) Enter(filename string) (leave func()) {
+	l.loading = append(l.loading, filename)
+	return func() { l.loading = l.loading[:len(l.loading)-1] }
+}
+
+// Returns the file imported as path: path itself if it's absolute, otherwise
+// the first match relative to the directory of from, then to SearchPath.
+func (l *Loader) resolve(path, from string) (string, error) {
+	if filepath.IsAbs(path) {
+		if isFile(path) {
+			return path, nil
+		}
+		return "", fmt.Errorf("cannot import %q: module not found", path)
+	}
+
+	dirs := append([]string{filepath.Dir(from)}, l.SearchPath...)
+	for _, dir := range dirs {
+		filename := filepath.Join(dir, path)
+		if isFile(filename) {
+			return filename, nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot import %q: module not found", path)
+}
+
+func (l *Loader) parse(path, filename string) (*ast.Program, error) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot import %q: %s", path, err)
+	}
+
+	p := parser.New(lexer.NewWithFilename(filename, string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf(
+			"cannot import %q: parser errors:\n\t%s",
+			path,
+			strings.Join(p.Errors(), "\n\t"),
+		)
+	}
+
+	if l.Expand == nil {
+		return program, nil
+	}
+
+	program, err = l.Expand(program)
+	if err != nil {
+		return nil, fmt.Errorf("cannot import %q: %s", path, err)
+	}
+	return program, nil
+}
+
+func isFile(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && !info.IsDir()
+}
+
+// Returns the names bound by the `export let` statements of a program.
+func ExportedNames(program *ast.Program) []string {
+	names := []string{}
+	for _, s := range program.Statements {
+		let, ok := s.(*ast.LetStatement)
+		if !ok || !let.Exported {
+			continue
+		}
+		for _, name := range let.Names() {
+			names = append(names, name.Value)
+		}
+	}
+	return names
+}
@@ -0,0 +1,206 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tjapit/monkey/src/ast"
+	"github.com/tjapit/monkey/src/lexer"
+	"github.com/tjapit/monkey/src/object"
+	"github.com/tjapit/monkey/src/parser"
+)
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"lib.mk":        "export let a = 1; let b = 2; export let c = 3;",
+		"sub/other.mk":  "export let d = 4;",
+		"path/found.mk": "export let e = 5;",
+	})
+
+	runs := 0
+	run := func(program *ast.Program) (func(string) (object.Object, bool), error) {
+		runs++
+		return func(name string) (object.Object, bool) {
+			return &object.String{Value: name}, true
+		}, nil
+	}
+
+	loader := NewLoader([]string{filepath.Join(dir, "path")})
+	from := filepath.Join(dir, "main.mk")
+
+	testCases := []struct {
+		desc     string
+		path     string
+		expected []string // exported names
+		runs     int      // total number of runs after the import
+	}{
+		{"Test 1", "lib.mk", []string{"a", "c"}, 1},
+		{"Test 2", "./lib.mk", []string{"a", "c"}, 1},
+		{"Test 3", "sub/other.mk", []string{"d"}, 2},
+		{"Test 4", "found.mk", []string{"e"}, 3},
+		{"Test 5", filepath.Join(dir, "lib.mk"), []string{"a", "c"}, 3},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			module, err := loader.Load(tC.path, from, run)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(module.Exports) != len(tC.expected) {
+				t.Fatalf(
+					"wrong number of exports. want=%d, got =%d",
+					len(tC.expected),
+					len(module.Exports),
+				)
+			}
+			for _, name := range tC.expected {
+				if _, ok := module.Field(name); !ok {
+					t.Errorf("module has no export %s", name)
+				}
+			}
+
+			if runs != tC.runs {
+				t.Errorf("wrong number of runs. want=%d, got =%d", tC.runs, runs)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.mk":   `import("b.mk");`,
+		"b.mk":   `import("a.mk");`,
+		"bad.mk": "let = 1;",
+	})
+
+	loader := NewLoader(nil)
+	var run Runner
+	run = func(program *ast.Program) (func(string) (object.Object, bool), error) {
+		// import like the engines do, relative to the module
+		for _, s := range program.Statements {
+			stmt, ok := s.(*ast.ExpressionStatement)
+			if !ok {
+				continue
+			}
+			if imp, ok := stmt.Expression.(*ast.ImportExpression); ok {
+				_, err := loader.Load(imp.Path, imp.Pos().Filename, run)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		return func(string) (object.Object, bool) { return nil, false }, nil
+	}
+
+	testCases := []struct {
+		desc     string
+		path     string
+		expected string
+	}{
+		{"Test 1", "missing.mk", `cannot import "missing.mk": module not found`},
+		{"Test 2", "a.mk", "import cycle: "},
+		{"Test 3", "bad.mk", `cannot import "bad.mk": parser errors:`},
+		{"Test 4", ".", `cannot import ".": module not found`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := loader.Load(tC.path, filepath.Join(dir, "main.mk"), run)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+
+			if !strings.HasPrefix(err.Error(), tC.expected) {
+				t.Errorf(
+					"wrong error. want prefix %q, got =%q",
+					tC.expected,
+					err.Error(),
+				)
+			}
+		})
+	}
+
+	// failed modules aren't cached, so a fixed module loads
+	err := os.WriteFile(filepath.Join(dir, "bad.mk"), []byte("let x = 1;"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loader.Load("bad.mk", filepath.Join(dir, "main.mk"), run)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestLoadEnteredFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.mk": `import("b.mk");`,
+		"b.mk": `import("a.mk");`,
+	})
+	entry := filepath.Join(dir, "a.mk")
+
+	loader := NewLoader(nil)
+	runs := 0
+	var run Runner
+	run = func(program *ast.Program) (func(string) (object.Object, bool), error) {
+		runs++
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		imp := stmt.Expression.(*ast.ImportExpression)
+		_, err := loader.Load(imp.Path, imp.Pos().Filename, run)
+		if err != nil {
+			return nil, err
+		}
+		return func(string) (object.Object, bool) { return nil, false }, nil
+	}
+
+	// run the entry file like repl.Run does
+	leave := loader.Enter(entry)
+	_, err := loader.Load("b.mk", entry, run)
+	leave()
+
+	expected := "import cycle: " + entry + " -> " + filepath.Join(dir, "b.mk") + " -> " + entry
+	if err == nil || err.Error() != expected {
+		t.Fatalf("wrong error. want=%q, got =%v", expected, err)
+	}
+	if runs != 1 {
+		t.Errorf("wrong number of runs. want=%d, got =%d", 1, runs)
+	}
+}
+
+func TestExportedNames(t *testing.T) {
+	input := `
+export let a = 1;
+let b = 2;
+export let c = fn() { let d = 3; };
+export let [e, ...f] = [4, 5];
+export let {g} = {"g": 6};
+`
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	names := ExportedNames(program)
+
+	if strings.Join(names, ",") != "a,c,e,f,g" {
+		t.Errorf("wrong exported names. want=%q, got =%q", "a,c,e,f,g", names)
+	}
+}
+
+// Writes the given files, by path relative to a temporary directory. Returns
+// the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(filename), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filename, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,45 @@
+package object
+
+import "fmt"
+
+// Checks the number of arguments passed to a function with numParams
+// parameters, the last numOptional of which have default values, followed by a
+// rest parameter if variadic.
+func CheckArity(numParams, numOptional int, variadic bool, numArgs int) error {
+	required := numParams - numOptional
+
+	switch {
+	case variadic && numArgs < required:
+		return fmt.Errorf(
+			"wrong number of arguments. want at least %d, got =%d",
+			required,
+			numArgs,
+		)
+	case variadic:
+		return nil
+	case numOptional == 0 && numArgs != numParams:
+		return fmt.Errorf(
+			"wrong number of arguments. want=%d, got =%d",
+			numParams,
+			numArgs,
+		)
+	case numArgs < required || numArgs > numParams:
+		return fmt.Errorf(
+			"wrong number of arguments. want=%d to %d, got =%d",
+			required,
+			numParams,
+			numArgs,
+		)
+	}
+
+	return nil
+}
+
+// Returns the elements of a spread argument, which must be an array.
+func SpreadArguments(val Object) ([]Object, error) {
+	array, ok := val.(*Array)
+	if !ok {
+		return nil, fmt.Errorf("cannot spread %s into arguments", val.Type())
+	}
+	return array.Elements, nil
+}
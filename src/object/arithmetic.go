@@ -0,0 +1,58 @@
+package object
+
+import (
+	"math"
+	"math/big"
+)
+
+// Returns a + b, wrapped around like Go's, and false if it overflowed int64.
+func AddInt64(a, b int64) (int64, bool) {
+	result := a + b
+	return result, (result > a) == (b > 0)
+}
+
+// Returns a - b, wrapped around like Go's, and false if it overflowed int64.
+func SubInt64(a, b int64) (int64, bool) {
+	result := a - b
+	return result, (result < a) == (b > 0)
+}
+
+// Returns a * b, wrapped around like Go's, and false if it overflowed int64.
+func MulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	result := a * b
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return result, false
+	}
+	return result, result/b == a
+}
+
+// Returns a / b, and false if it overflowed int64. b must not be zero.
+func DivInt64(a, b int64) (int64, bool) {
+	return a / b, !(a == math.MinInt64 && b == -1)
+}
+
+// Returns -a, and false if it overflowed int64.
+func NegInt64(a int64) (int64, bool) {
+	return -a, a != math.MinInt64
+}
+
+// Returns v as an Integer if it fits int64, as a BigInt otherwise. Results of
+// BigInt arithmetic go through here so each value has a single representation.
+func NewInteger(v *big.Int) Object {
+	if v.IsInt64() {
+		return &Integer{Value: v.Int64()}
+	}
+	return &BigInt{Value: v}
+}
+
+// Returns the value of an Integer or BigInt. The result must not be modified.
+func BigValue(obj Object) *big.Int {
+	if i, ok := obj.(*Integer); ok {
+		return big.NewInt(i.Value)
+	}
+	return obj.(*BigInt).Value
+}
@@ -0,0 +1,192 @@
+package object
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Builtin functions shared by the evaluator and the VM. The compiler refers to
+// them by their index, so only ever append to this slice.
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{
+		"len",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError(
+					"wrong number of arguments. want=%d, got =%d",
+					1,
+					len(args),
+				)
+			}
+
+			switch arg := args[0].(type) {
+			case *String:
+				return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *Array:
+				return &Integer{Value: int64(len(arg.Elements))}
+			default:
+				return newError("argument to `len` not supported, got =%s", args[0].Type())
+			}
+		}},
+	},
+	{
+		"puts",
+		&Builtin{Fn: func(args ...Object) Object {
+			for _, arg := range args {
+				fmt.Println(arg.Inspect())
+			}
+
+			return nil
+		}},
+	},
+	{
+		"first",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError(
+					"wrong number of arguments. want=%d, got =%d",
+					1,
+					len(args),
+				)
+			}
+
+			if args[0].Type() != ARRAY_OBJ {
+				return newError(
+					"argument to `first` must be ARRAY, got =%s",
+					args[0].Type(),
+				)
+			}
+
+			arr := args[0].(*Array).Elements
+			if len(arr) > 0 {
+				return arr[0]
+			}
+
+			return nil
+		}},
+	},
+	{
+		"last",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError(
+					"wrong number of arguments. want=%d, got =%d",
+					1,
+					len(args),
+				)
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError(
+					"argument to `last` must be ARRAY, got =%s",
+					args[0].Type(),
+				)
+			}
+
+			arr := args[0].(*Array).Elements
+			if len(arr) > 0 {
+				return arr[len(arr)-1]
+			}
+			return nil
+		}},
+	},
+	{
+		"rest",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError(
+					"wrong number of arguments. want=%d, got =%d",
+					1,
+					len(args),
+				)
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError(
+					"argument to `rest` must be ARRAY, got =%s",
+					args[0].Type(),
+				)
+			}
+
+			arr := args[0].(*Array).Elements
+			if len(arr) > 0 {
+				return &Array{Elements: arr[1:]}
+			}
+			return nil
+		}},
+	},
+	{
+		"push",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 2 {
+				return newError(
+					"wrong number of arguments. want=%d, got =%d",
+					2,
+					len(args),
+				)
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError(
+					"first argument to `push` must be ARRAY, got =%s",
+					args[0].Type(),
+				)
+			}
+
+			arr := args[0].(*Array)
+			arr.Elements = append(arr.Elements, args[1])
+
+			return arr
+		}},
+	},
+	{
+		"error",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError(
+					"wrong number of arguments. want=1 or 2, got =%d",
+					len(args),
+				)
+			}
+
+			msg, ok := args[0].(*String)
+			if !ok {
+				return newError(
+					"first argument to `error` must be STRING, got =%s",
+					args[0].Type(),
+				)
+			}
+
+			kind := USER_ERROR
+			if len(args) == 2 {
+				k, ok := args[1].(*String)
+				if !ok {
+					return newError(
+						"second argument to `error` must be STRING, got =%s",
+						args[1].Type(),
+					)
+				}
+				kind = k.Value
+			}
+
+			return &Error{Kind: kind, Message: msg.Value}
+		}},
+	},
+}
+
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+	return nil
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{
+		Kind:    RUNTIME_ERROR,
+		Message: fmt.Sprintf(format, a...),
+		Thrown:  true,
+	}
+}
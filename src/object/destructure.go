@@ -0,0 +1,59 @@
+package object
+
+import "fmt"
+
+// Returns the first numElements elements of an array, followed by an array of
+// the remaining ones if withRest is set. Without a rest the array must have
+// exactly numElements elements, with a rest at least that many.
+func DestructureArray(val Object, numElements int, withRest bool) ([]Object, error) {
+	array, ok := val.(*Array)
+	if !ok {
+		return nil, fmt.Errorf("cannot destructure %s as an array", val.Type())
+	}
+
+	got := len(array.Elements)
+	if !withRest && got != numElements {
+		return nil, fmt.Errorf(
+			"wrong number of elements to destructure. want=%d, got =%d",
+			numElements,
+			got,
+		)
+	}
+	if withRest && got < numElements {
+		return nil, fmt.Errorf(
+			"too few elements to destructure. want at least %d, got =%d",
+			numElements,
+			got,
+		)
+	}
+
+	values := make([]Object, numElements, numElements+1)
+	copy(values, array.Elements)
+
+	if withRest {
+		rest := make([]Object, got-numElements)
+		copy(rest, array.Elements[numElements:])
+		values = append(values, &Array{Elements: rest})
+	}
+
+	return values, nil
+}
+
+// Returns the values of a hash's string keys, each of which must be present.
+func DestructureHash(val Object, keys []string) ([]Object, error) {
+	hash, ok := val.(*Hash)
+	if !ok {
+		return nil, fmt.Errorf("cannot destructure %s as a hash", val.Type())
+	}
+
+	values := make([]Object, len(keys))
+	for i, key := range keys {
+		pair, ok := hash.Pairs[(&String{Value: key}).HashKey()]
+		if !ok {
+			return nil, fmt.Errorf("missing key %q in destructured hash", key)
+		}
+		values[i] = pair.Value
+	}
+
+	return values, nil
+}
@@ -1,13 +1,44 @@
 package object
 
+import "github.com/tjapit/monkey/src/ast"
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	outer  *Environment
+	config *EvalConfig
+}
+
+// Settings of an evaluation, shared by all environments enclosed by the one
+// it's created with.
+type EvalConfig struct {
+	// Makes integer arithmetic that overflows int64 an error instead of
+	// promoting the result to a BigInt.
+	CheckedArithmetic bool
+
+	// Loads the modules of import expressions. Optional.
+	Modules ModuleLoader
+}
+
+// Loads the module imported as path by the file from, running its program with
+// run unless it's cached. Implemented by module.Loader.
+type ModuleLoader interface {
+	Load(
+		path, from string,
+		run func(program *ast.Program) (lookup func(name string) (Object, bool), err error),
+	) (*Module, error)
 }
 
 func NewEnvironment() *Environment {
+	return NewEnvironmentWithConfig(&EvalConfig{})
+}
+
+func NewEnvironmentWithConfig(config *EvalConfig) *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	return &Environment{store: s, outer: nil, config: config}
+}
+
+func (e *Environment) Config() *EvalConfig {
+	return e.config
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -23,8 +54,35 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// Updates an existing binding in the scope that defined it rather than
+// shadowing it. Reports false if name isn't bound in any enclosing scope.
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
+
+// Returns a copy of the environment's own bindings, see Restore.
+func (e *Environment) Snapshot() map[string]Object {
+	s := make(map[string]Object, len(e.store))
+	for name, val := range e.store {
+		s[name] = val
+	}
+	return s
+}
+
+// Replaces the environment's own bindings by a Snapshot taken earlier.
+func (e *Environment) Restore(snapshot map[string]Object) {
+	e.store = snapshot
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
+	env := NewEnvironmentWithConfig(outer.config)
 	env.outer = outer
 	return env
 }
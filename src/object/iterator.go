@@ -0,0 +1,70 @@
+package object
+
+import "fmt"
+
+// Steps through the elements of an array, string or hash for a for-in loop.
+type Iterator struct {
+	keys   []Object // indexes, or keys for hashes
+	values []Object
+	isHash bool
+	pos    int
+}
+
+// Creates an Iterator over a snapshot of the collection's elements. Hashes are
+// iterated in key order. Reports false if obj can't be iterated over.
+func NewIterator(obj Object) (*Iterator, bool) {
+	it := &Iterator{pos: -1}
+
+	switch obj := obj.(type) {
+	case *Array:
+		for i, el := range obj.Elements {
+			it.keys = append(it.keys, &Integer{Value: int64(i)})
+			it.values = append(it.values, el)
+		}
+	case *String:
+		i := int64(0)
+		for _, r := range obj.Value {
+			it.keys = append(it.keys, &Integer{Value: i})
+			it.values = append(it.values, &String{Value: string(r)})
+			i++
+		}
+	case *Hash:
+		for _, pair := range obj.SortedPairs() {
+			it.keys = append(it.keys, pair.Key)
+			it.values = append(it.values, pair.Value)
+		}
+		it.isHash = true
+	default:
+		return nil, false
+	}
+
+	return it, true
+}
+
+func (it *Iterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *Iterator) Inspect() string {
+	return fmt.Sprintf("Iterator[%p]", it)
+}
+
+// Advances to the next element. Reports false when there are none left.
+func (it *Iterator) Next() bool {
+	if it.pos < len(it.keys) {
+		it.pos++
+	}
+	return it.pos < len(it.keys)
+}
+
+// Returns the index or hash key of the current element.
+func (it *Iterator) Key() Object { return it.keys[it.pos] }
+
+// Returns the current element, or the value for hashes.
+func (it *Iterator) Value() Object { return it.values[it.pos] }
+
+// Returns what a single loop variable is bound to: the current element, or
+// the key for hashes.
+func (it *Iterator) Element() Object {
+	if it.isHash {
+		return it.Key()
+	}
+	return it.Value()
+}
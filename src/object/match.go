@@ -0,0 +1,137 @@
+package object
+
+import (
+	"math/big"
+
+	"github.com/tjapit/monkey/src/ast"
+)
+
+// Matches val against the pattern of a match arm. Returns the values bound by
+// the pattern, in the order of its Names, and whether val matched.
+func Match(pattern ast.Pattern, val Object) ([]Object, bool) {
+	bindings := []Object{}
+	if !match(pattern, val, &bindings) {
+		return nil, false
+	}
+	return bindings, true
+}
+
+func match(pattern ast.Pattern, val Object, bindings *[]Object) bool {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return true
+
+	case *ast.BindingPattern:
+		*bindings = append(*bindings, val)
+		return true
+
+	case *ast.LiteralPattern:
+		literal := literalValue(pattern.Value)
+		return literal != nil && literalEquals(literal, val)
+
+	case *ast.ArrayPattern:
+		array, ok := val.(*Array)
+		if !ok {
+			return false
+		}
+
+		numElements := len(pattern.Elements)
+		if len(array.Elements) < numElements ||
+			pattern.Rest == nil && len(array.Elements) != numElements {
+			return false
+		}
+
+		for i, element := range pattern.Elements {
+			if !match(element, array.Elements[i], bindings) {
+				return false
+			}
+		}
+
+		if pattern.Rest != nil {
+			rest := make([]Object, len(array.Elements)-numElements)
+			copy(rest, array.Elements[numElements:])
+			return match(pattern.Rest, &Array{Elements: rest}, bindings)
+		}
+		return true
+
+	case *ast.HashPattern:
+		hash, ok := val.(*Hash)
+		if !ok {
+			return false
+		}
+
+		// the hash may have more keys than the pattern
+		for _, pair := range pattern.Pairs {
+			found, ok := hash.Pairs[(&String{Value: pair.Key}).HashKey()]
+			if !ok || !match(pair.Value, found.Value, bindings) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return false
+	}
+}
+
+// Returns the value of a literal pattern's expression, nil if it's not a
+// literal.
+func literalValue(node ast.Expression) Object {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return &Integer{Value: node.Value}
+	case *ast.BigIntLiteral:
+		return &BigInt{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &Float{Value: node.Value}
+	case *ast.StringLiteral:
+		return &String{Value: node.Value}
+	case *ast.Boolean:
+		return &Boolean{Value: node.Value}
+	case *ast.PrefixExpression:
+		if node.Operator != "-" {
+			return nil
+		}
+		switch right := literalValue(node.Right).(type) {
+		case *Integer, *BigInt:
+			return NewInteger(new(big.Int).Neg(BigValue(right)))
+		case *Float:
+			return &Float{Value: -right.Value}
+		}
+	}
+	return nil
+}
+
+// Reports whether val equals the literal like `==` does, comparing integers
+// and floats by their numeric value.
+func literalEquals(literal, val Object) bool {
+	switch literal := literal.(type) {
+	case *Integer, *BigInt:
+		switch val.(type) {
+		case *Integer, *BigInt:
+			return BigValue(literal).Cmp(BigValue(val)) == 0
+		case *Float:
+			return floatValue(literal) == floatValue(val)
+		}
+	case *Float:
+		switch val.(type) {
+		case *Integer, *BigInt, *Float:
+			return literal.Value == floatValue(val)
+		}
+	case *String:
+		s, ok := val.(*String)
+		return ok && s.Value == literal.Value
+	case *Boolean:
+		b, ok := val.(*Boolean)
+		return ok && b.Value == literal.Value
+	}
+	return false
+}
+
+func floatValue(obj Object) float64 {
+	if f, ok := obj.(*Float); ok {
+		return f.Value
+	}
+	value, _ := new(big.Float).SetInt(BigValue(obj)).Float64()
+	return value
+}
@@ -4,24 +4,46 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/tjapit/monkey/src/ast"
+	"github.com/tjapit/monkey/src/code"
+	"github.com/tjapit/monkey/src/token"
 )
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	BIGINT_OBJ       = "BIGINT"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	CLOSURE_OBJ           = "CLOSURE"
+	CELL_OBJ              = "CELL"
+	ITERATOR_OBJ          = "ITERATOR"
+
+	QUOTE_OBJ = "QUOTE"
+	MACRO_OBJ = "MACRO"
+
+	MODULE_OBJ = "MODULE"
+
+	PATTERN_OBJ = "PATTERN"
 )
 
 type Object interface {
@@ -36,6 +58,31 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+// Integer outside of the int64 range. Arithmetic results that fit int64 are
+// Integers again, see NewInteger.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() ObjectType { return BIGINT_OBJ }
+func (b *BigInt) Inspect() string  { return b.Value.String() }
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Formats the shortest representation that round-trips, always keeping a
+// decimal point or exponent so floats are distinguishable from integers.
+func (f *Float) Inspect() string {
+	out := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(out, ".eIN") { // e.g. "3", but not "1e+21", "+Inf", "NaN"
+		out += ".0"
+	}
+	return out
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -55,15 +102,87 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Signals a `break` out of the innermost loop while evaluating its body.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+// Signals a `continue` with the next iteration of the innermost loop.
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
+// Kinds of errors. Users can pick their own kind with error(msg, kind).
+const (
+	RUNTIME_ERROR = "RuntimeError" // raised by the interpreter itself
+	USER_ERROR    = "Error"        // created by error(msg) or throw
+)
+
 type Error struct {
+	Kind    string
 	Message string
+	Pos     token.Position // where the error occurred, if known
+
+	// Thrown errors propagate until they're caught. Others are plain values,
+	// e.g. the result of error(msg) or the error bound by a catch.
+	Thrown bool
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
+
+// Implements the error interface so the VM can raise Errors as Go errors.
+func (e *Error) Error() string { return e.Message }
+
+// Returns a copy of the error that is thrown, or held as a value.
+func (e *Error) WithThrown(thrown bool) *Error {
+	c := *e
+	c.Thrown = thrown
+	return &c
+}
+
+// Returns the error's "message", "kind" or "position" for index expressions
+// like `e["message"]`. Reports false for unknown fields and unknown positions.
+func (e *Error) Field(name string) (Object, bool) {
+	switch name {
+	case "message":
+		return &String{Value: e.Message}, true
+	case "kind":
+		return &String{Value: e.Kind}, true
+	case "position":
+		if !e.Pos.IsValid() {
+			return nil, false
+		}
+		return &String{Value: e.Pos.String()}, true
+	default:
+		return nil, false
+	}
+}
+
+// Converts a thrown value into an Error. Errors keep their kind, message and
+// position, anything else becomes a USER_ERROR with the value as message.
+func NewThrownError(val Object) *Error {
+	switch val := val.(type) {
+	case *Error:
+		return val.WithThrown(true)
+	case *String:
+		return &Error{Kind: USER_ERROR, Message: val.Value, Thrown: true}
+	default:
+		return &Error{Kind: USER_ERROR, Message: val.Inspect(), Thrown: true}
+	}
+}
 
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // default values of the last len(Defaults) parameters
+	Rest       *ast.Identifier  // bound to an array of the remaining arguments, if set
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -72,20 +191,143 @@ func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
+	out.WriteString("fn(")
+	out.WriteString(ast.FormatParameters(f.Parameters, f.Defaults, f.Rest))
+	out.WriteString(") {\n")
+	out.WriteString(f.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+// Unevaluated code, the result of quote(...).
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
 	params := []string{}
-	for _, p := range f.Parameters {
+	for _, p := range m.Parameters {
 		params = append(params, p.String())
 	}
 
-	out.WriteString("fn(")
+	out.WriteString("macro(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
-	out.WriteString(f.Body.String())
+	out.WriteString(m.Body.String())
 	out.WriteString("\n}")
 
 	return out.String()
 }
 
+// Module loaded by an import expression: the exported top-level bindings of
+// the file at Path. Exports are looked up when accessed, so assignments made
+// after the import, e.g. by the module's functions, are visible.
+type Module struct {
+	Path    string
+	Exports []string                         // exported names
+	Lookup  func(name string) (Object, bool) // top-level binding of the module
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module(" + m.Path + ")" }
+
+// Returns the exported binding for index expressions like `m["name"]`.
+// Reports false if the module doesn't export name.
+func (m *Module) Field(name string) (Object, bool) {
+	for _, export := range m.Exports {
+		if export == name {
+			return m.Lookup(name)
+		}
+	}
+	return nil, false
+}
+
+// Pattern of a match arm, the operand of the VM's OpMatch.
+type Pattern struct {
+	Pattern ast.Pattern
+}
+
+func (p *Pattern) Type() ObjectType { return PATTERN_OBJ }
+func (p *Pattern) Inspect() string  { return "PATTERN(" + p.Pattern.String() + ")" }
+
+// Function compiled to bytecode by the compiler, executed by the VM.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	LocalNames    []string // names of the local variables by index
+	FreeNames     []string // names of the free variables by index
+	NumParameters int
+	NumOptional   int  // number of trailing parameters with default values
+	Variadic      bool // a rest parameter follows the others
+	SourceMap     code.SourceMap
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// CompiledFunction bundled with the free variables it captured when created.
+type Closure struct {
+	Fn      *CompiledFunction
+	Free    []*Cell
+	Globals *Globals // of the program the closure was created in
+}
+
+// Constants and global bindings of a compiled program. Instructions refer to
+// them by index, so closures keep those of their own program when they're
+// called from an importing one.
+type Globals struct {
+	Constants []Object
+	Values    []Object
+	Names     []string // names of the global variables by index
+}
+
+func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
+func (c *Closure) Inspect() string  { return fmt.Sprintf("Closure[%p]", c) }
+
+// Variable captured by closures. While the function declaring it runs, Value
+// points to its slot on the stack, so the function and its closures see each
+// other's assignments. When the function returns, the cell is closed: it keeps
+// the last value itself.
+type Cell struct {
+	Value  *Object
+	closed Object
+}
+
+// Creates a cell that holds the value itself.
+func NewClosedCell(value Object) *Cell {
+	c := &Cell{closed: value}
+	c.Value = &c.closed
+	return c
+}
+
+// Detaches the cell from the stack slot it points to.
+func (c *Cell) Close() {
+	c.closed = *c.Value
+	c.Value = &c.closed
+}
+
+func (c *Cell) Type() ObjectType { return CELL_OBJ }
+func (c *Cell) Inspect() string  { return fmt.Sprintf("Cell[%p]", c) }
+
+// UTF-8 encoded string. Lengths and indices of Strings count runes, not
+// bytes, see RuneAt.
 type String struct {
 	Value string
 }
@@ -93,6 +335,23 @@ type String struct {
 func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
 
+// Returns the rune at rune index i as a String, or false if i is out of range.
+func (s *String) RuneAt(i int64) (*String, bool) {
+	if i < 0 {
+		return nil, false
+	}
+
+	var idx int64
+	for _, r := range s.Value {
+		if idx == i {
+			return &String{Value: string(r)}, true
+		}
+		idx++
+	}
+
+	return nil, false
+}
+
 type (
 	BuiltinFunction func(args ...Object) Object
 	Builtin         struct {
@@ -156,6 +415,31 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// Floats with an integral value hash like the equal Integer, so 1 and 1.0
+// address the same hash entry, just like 1 == 1.0.
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) &&
+		f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+	}
+	if f.Value == math.Trunc(f.Value) && !math.IsInf(f.Value, 0) {
+		value, _ := big.NewFloat(f.Value).Int(nil)
+		return (&BigInt{Value: value}).HashKey()
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
+// BigInts in the int64 range hash like the equal Integer.
+func (b *BigInt) HashKey() HashKey {
+	if b.Value.IsInt64() {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(b.Value.Int64())}
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -180,3 +464,60 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+// Returns the pairs of the hash ordered by key. Numbers sort by value, strings
+// and booleans naturally, and keys of different types by type name.
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return keyLess(pairs[i].Key, pairs[j].Key)
+	})
+
+	return pairs
+}
+
+func keyLess(a, b Object) bool {
+	if isInteger(a) && isInteger(b) {
+		return BigValue(a).Cmp(BigValue(b)) < 0
+	}
+	if x, ok := numberValue(a); ok {
+		if y, ok := numberValue(b); ok {
+			return x < y
+		}
+	}
+
+	switch a := a.(type) {
+	case *String:
+		if b, ok := b.(*String); ok {
+			return a.Value < b.Value
+		}
+	case *Boolean:
+		if b, ok := b.(*Boolean); ok {
+			return !a.Value && b.Value
+		}
+	}
+
+	return a.Type() < b.Type()
+}
+
+func numberValue(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value, true
+	case *Float:
+		return obj.Value, true
+	default:
+		return 0, false
+	}
+}
+
+func isInteger(obj Object) bool {
+	return obj.Type() == INTEGER_OBJ || obj.Type() == BIGINT_OBJ
+}
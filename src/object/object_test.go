@@ -1,6 +1,13 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/tjapit/monkey/src/token"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -18,3 +25,298 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestStringRuneAt(t *testing.T) {
+	str := &String{Value: "h🐒llo"}
+
+	testCases := []struct {
+		desc     string
+		index    int64
+		expected string
+		ok       bool
+	}{
+		{"Test 1", 0, "h", true},
+		{"Test 2", 1, "🐒", true},
+		{"Test 3", 4, "o", true},
+		{"Test 4", 5, "", false},
+		{"Test 5", -1, "", false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			char, ok := str.RuneAt(tC.index)
+			if ok != tC.ok {
+				t.Fatalf("wrong ok. want=%t, got =%t", tC.ok, ok)
+			}
+			if ok && char.Value != tC.expected {
+				t.Errorf("wrong rune. want=%q, got =%q", tC.expected, char.Value)
+			}
+		})
+	}
+}
+
+func TestFloatHashKey(t *testing.T) {
+	half1 := &Float{Value: 0.5}
+	half2 := &Float{Value: 0.5}
+	quarter := &Float{Value: 0.25}
+
+	if half1.HashKey() != half2.HashKey() {
+		t.Errorf("floats with the same value have different hash keys")
+	}
+	if half1.HashKey() == quarter.HashKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+
+	one := &Float{Value: 1}
+	if one.HashKey() != (&Integer{Value: 1}).HashKey() {
+		t.Errorf("integral float and equal integer have different hash keys")
+	}
+}
+
+func TestBigIntHashKey(t *testing.T) {
+	huge, _ := new(big.Int).SetString("100000000000000000000", 10)
+	huge1 := &BigInt{Value: huge}
+	huge2 := &BigInt{Value: new(big.Int).Set(huge)}
+
+	if huge1.HashKey() != huge2.HashKey() {
+		t.Errorf("big integers with the same value have different hash keys")
+	}
+	if huge1.HashKey() != (&Float{Value: 1e20}).HashKey() {
+		t.Errorf("integral float and equal big integer have different hash keys")
+	}
+
+	one := &BigInt{Value: big.NewInt(1)}
+	if one.HashKey() != (&Integer{Value: 1}).HashKey() {
+		t.Errorf("big integer and equal integer have different hash keys")
+	}
+}
+
+func TestNewInteger(t *testing.T) {
+	if _, ok := NewInteger(big.NewInt(math.MinInt64)).(*Integer); !ok {
+		t.Errorf("int64 value is not an Integer")
+	}
+
+	value := new(big.Int).Add(big.NewInt(math.MaxInt64), big.NewInt(1))
+	if _, ok := NewInteger(value).(*BigInt); !ok {
+		t.Errorf("value beyond int64 is not a BigInt")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    float64
+		expected string
+	}{
+		{"Test 1", 3.14, "3.14"},
+		{"Test 2", 3, "3.0"},
+		{"Test 3", -0.5, "-0.5"},
+		{"Test 4", 1e21, "1e+21"},
+		{"Test 5", 1e-9, "1e-09"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			f := &Float{Value: tC.value}
+			if f.Inspect() != tC.expected {
+				t.Errorf("wrong Inspect. want=%q, got =%q", tC.expected, f.Inspect())
+			}
+		})
+	}
+}
+
+func TestIterator(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		collection       Object
+		expectedKeys     []string
+		expectedElements []string
+	}{
+		{
+			"Test 1",
+			&Array{Elements: []Object{&Integer{Value: 5}, &String{Value: "a"}}},
+			[]string{"0", "1"},
+			[]string{"5", "a"},
+		},
+		{
+			"Test 2",
+			&String{Value: "h🐒"},
+			[]string{"0", "1"},
+			[]string{"h", "🐒"},
+		},
+		{
+			"Test 3",
+			hashOf(&String{Value: "b"}, &Integer{Value: 10}, &Float{Value: 1.5}, &Boolean{Value: true}),
+			[]string{"true", "1.5", "10", "b"},
+			[]string{"true", "1.5", "10", "b"},
+		},
+		{"Test 4", &Array{}, nil, nil},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			it, ok := NewIterator(tC.collection)
+			if !ok {
+				t.Fatalf("NewIterator failed for %s", tC.collection.Type())
+			}
+
+			keys := []string{}
+			elements := []string{}
+			for it.Next() {
+				keys = append(keys, it.Key().Inspect())
+				elements = append(elements, it.Element().Inspect())
+			}
+
+			if strings.Join(keys, " ") != strings.Join(tC.expectedKeys, " ") {
+				t.Errorf("wrong keys. want=%v, got =%v", tC.expectedKeys, keys)
+			}
+			if strings.Join(elements, " ") != strings.Join(tC.expectedElements, " ") {
+				t.Errorf("wrong elements. want=%v, got =%v", tC.expectedElements, elements)
+			}
+			if it.Next() {
+				t.Errorf("exhausted iterator advanced")
+			}
+		})
+	}
+
+	if _, ok := NewIterator(&Integer{Value: 1}); ok {
+		t.Errorf("NewIterator succeeded for INTEGER")
+	}
+}
+
+func TestErrorField(t *testing.T) {
+	err := &Error{
+		Kind:    RUNTIME_ERROR,
+		Message: "boom",
+		Pos:     token.Position{Filename: "a.mk", Line: 2, Column: 7},
+	}
+
+	testCases := []struct {
+		desc     string
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"Test 1", "message", "boom", true},
+		{"Test 2", "kind", "RuntimeError", true},
+		{"Test 3", "position", "a.mk:2:7", true},
+		{"Test 4", "stack", "", false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			field, ok := err.Field(tC.name)
+			if ok != tC.ok {
+				t.Fatalf("wrong ok. want=%t, got =%t", tC.ok, ok)
+			}
+			if ok && field.(*String).Value != tC.expected {
+				t.Errorf("wrong field. want=%q, got =%q", tC.expected, field.Inspect())
+			}
+		})
+	}
+
+	if _, ok := (&Error{Message: "x"}).Field("position"); ok {
+		t.Errorf("error without position has position field")
+	}
+}
+
+func TestNewThrownError(t *testing.T) {
+	value := &Error{Kind: "Custom", Message: "a"}
+
+	testCases := []struct {
+		desc            string
+		value           Object
+		expectedKind    string
+		expectedMessage string
+	}{
+		{"Test 1", value, "Custom", "a"},
+		{"Test 2", &String{Value: "oops"}, USER_ERROR, "oops"},
+		{"Test 3", &Integer{Value: 42}, USER_ERROR, "42"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := NewThrownError(tC.value)
+			if !err.Thrown {
+				t.Errorf("error is not thrown")
+			}
+			if err.Kind != tC.expectedKind {
+				t.Errorf("wrong kind. want=%q, got =%q", tC.expectedKind, err.Kind)
+			}
+			if err.Message != tC.expectedMessage {
+				t.Errorf("wrong message. want=%q, got =%q", tC.expectedMessage, err.Message)
+			}
+		})
+	}
+
+	if value.Thrown {
+		t.Errorf("throwing an error value modified it")
+	}
+}
+
+func TestToNodeErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		obj      Object
+		expected string
+	}{
+		{"Test 1", &Array{}, "cannot unquote ARRAY"},
+		{"Test 2", nil, "cannot unquote an expression without a value"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := ToNode(tC.obj)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if err.Error() != tC.expected {
+				t.Errorf("wrong error. want=%q, got =%q", tC.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestInt64Arithmetic(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		fn       func(a, b int64) (int64, bool)
+		a, b     int64
+		expected int64
+		ok       bool
+	}{
+		{"Test 1", AddInt64, 1, 2, 3, true},
+		{"Test 2", AddInt64, math.MaxInt64, 1, math.MinInt64, false},
+		{"Test 3", AddInt64, math.MinInt64, -1, math.MaxInt64, false},
+		{"Test 4", SubInt64, math.MinInt64, 1, math.MaxInt64, false},
+		{"Test 5", SubInt64, 0, math.MinInt64, math.MinInt64, false},
+		{"Test 6", SubInt64, -1, math.MaxInt64, math.MinInt64, true},
+		{"Test 7", MulInt64, 1 << 32, 1 << 31, math.MinInt64, false},
+		{"Test 8", MulInt64, -(1 << 32), 1 << 31, math.MinInt64, true},
+		{"Test 9", MulInt64, -1, math.MinInt64, math.MinInt64, false},
+		{"Test 10", MulInt64, 0, math.MinInt64, 0, true},
+		{"Test 11", DivInt64, math.MinInt64, -1, math.MinInt64, false},
+		{"Test 12", DivInt64, math.MinInt64, 2, math.MinInt64 / 2, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result, ok := tC.fn(tC.a, tC.b)
+			if result != tC.expected || ok != tC.ok {
+				t.Errorf(
+					"wrong result. want=%d %t, got =%d %t",
+					tC.expected,
+					tC.ok,
+					result,
+					ok,
+				)
+			}
+		})
+	}
+
+	if _, ok := NegInt64(math.MinInt64); ok {
+		t.Errorf("negating MinInt64 doesn't overflow")
+	}
+}
+
+// Builds a hash mapping each key to itself.
+func hashOf(keys ...Object) *Hash {
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, k := range keys {
+		hash.Pairs[k.(Hashable).HashKey()] = HashPair{Key: k, Value: k}
+	}
+	return hash
+}
@@ -0,0 +1,66 @@
+package object
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tjapit/monkey/src/ast"
+	"github.com/tjapit/monkey/src/token"
+)
+
+// Reports whether node is a call like `quote(x)`.
+func IsQuoteCall(node ast.Node) bool {
+	return isCallOf(node, "quote")
+}
+
+// Reports whether node is a call like `unquote(x)`, which evaluates x inside of
+// quoted code.
+func IsUnquoteCall(node ast.Node) bool {
+	return isCallOf(node, "unquote")
+}
+
+func isCallOf(node ast.Node, name string) bool {
+	call, ok := node.(*ast.CallExpression)
+	if !ok || len(call.Arguments) != 1 {
+		return false
+	}
+	if _, ok := call.Arguments[0].(*ast.SpreadExpression); ok {
+		return false // the spread array may hold any number of arguments
+	}
+
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+// Turns the value of an unquote(...) call back into code. Quotes are replaced
+// by the code they hold, other values by the equal literal.
+func ToNode(obj Object) (ast.Expression, error) {
+	switch obj := obj.(type) {
+	case nil:
+		return nil, fmt.Errorf("cannot unquote an expression without a value")
+	case *Integer:
+		t := token.Token{Type: token.INT, Literal: strconv.FormatInt(obj.Value, 10)}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, nil
+	case *BigInt:
+		t := token.Token{Type: token.INT, Literal: obj.Value.String()}
+		return &ast.BigIntLiteral{Token: t, Value: obj.Value}, nil
+	case *Float:
+		t := token.Token{Type: token.FLOAT, Literal: obj.Inspect()}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}, nil
+	case *String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}, nil
+	case *Boolean:
+		t := token.Token{Type: token.FALSE, Literal: "false"}
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}, nil
+	case *Quote:
+		if expression, ok := obj.Node.(ast.Expression); ok {
+			return expression, nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot unquote %s", obj.Type())
+}
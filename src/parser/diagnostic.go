@@ -0,0 +1,25 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/tjapit/monkey/src/token"
+)
+
+// Upper bound on diagnostics with the same message collected per parse. Further
+// repeats are dropped and counted in a final "too many errors" diagnostic.
+const MaxRepeatedDiagnostics = 3
+
+// Single parser error.
+type Diagnostic struct {
+	Pos     token.Position
+	Message string
+
+	// token types involved in the error, empty if not applicable
+	Expected token.TokenType
+	Got      token.TokenType
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Message)
+}
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/tjapit/monkey/src/ast"
@@ -13,6 +15,9 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // = or +=
+	OR          // ||
+	AND         // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // +
@@ -24,16 +29,27 @@ const (
 
 // map of tokens to their precedences
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.ASTERISK: PRODUCT,
-	token.SLASH:    PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.PERCENT_ASSIGN:  ASSIGN,
+	token.OR:              OR,
+	token.AND:             AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.ASTERISK:        PRODUCT,
+	token.SLASH:           PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
 }
 
 type (
@@ -44,7 +60,14 @@ type (
 type Parser struct {
 	l *lexer.Lexer
 
-	errors []string
+	diagnostics []Diagnostic
+	warnings    []Diagnostic
+	dropped     []Diagnostic // repeats over MaxRepeatedDiagnostics
+	panicking   bool         // an error occurred and the statement is not yet recovered
+	blockDepth  int          // number of enclosing block statements
+	loopDepth   int          // number of enclosing loops in the current function
+
+	comments []token.Token
 
 	curToken  token.Token
 	peekToken token.Token
@@ -56,7 +79,7 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:              l,
-		errors:         []string{},
+		diagnostics:    []Diagnostic{},
 		prefixParseFns: make(map[token.TokenType]prefixParseFn),
 		infixParseFns:  make(map[token.TokenType]infixParseFn),
 	}
@@ -64,26 +87,43 @@ func New(l *lexer.Lexer) *Parser {
 	// register parsing functions for expressions
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.IMPORT, p.parseImportExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 
 	// infix parse functions
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
@@ -125,6 +165,32 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	return hash
 }
 
+// Parses an assignment. Assignments are right-associative, so `a = b = 1`
+// assigns 1 to both.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   target,
+	}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		p.addError(Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: fmt.Sprintf("invalid assignment target: %s", target),
+			Got:     p.curToken.Type,
+		})
+		return nil
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
 
@@ -144,6 +210,17 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	return array
 }
 
+// Reports tokens the lexer could not make sense of. Their literal is either
+// the offending character or a description of the malformed literal.
+func (p *Parser) parseIllegal() ast.Expression {
+	p.addError(Diagnostic{
+		Pos:     p.curToken.Pos,
+		Message: fmt.Sprintf("illegal token: %s", p.curToken.Literal),
+		Got:     token.ILLEGAL,
+	})
+	return nil
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
@@ -173,14 +250,92 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
-	return &ast.CallExpression{
-		Token:     p.curToken,
-		Function:  function,
-		Arguments: p.parseExpressionList(token.RPAREN),
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseCallArguments()
+	return exp
+}
+
+// Parses the arguments of a call like an expression list, except that each
+// argument may be spread, `...args`.
+func (p *Parser) parseCallArguments() []ast.Expression {
+	args := []ast.Expression{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return args
+	}
+
+	p.nextToken()
+	args = append(args, p.parseCallArgument())
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		args = append(args, p.parseCallArgument())
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return args
+}
+
+func (p *Parser) parseCallArgument() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+
+	spread := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	return spread
+}
+
+// Parses the parameters of a function literal into function. Parameters may
+// have default values, `b = 10`, which all following ones must have too. A
+// last `...rest` collects the remaining arguments.
+func (p *Parser) parseFunctionParameters(function *ast.FunctionLiteral) bool {
+	function.Parameters = []*ast.Identifier{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			function.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		function.Parameters = append(function.Parameters, param)
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			function.Defaults = append(function.Defaults, p.parseExpression(LOWEST))
+		} else if len(function.Defaults) > 0 {
+			p.addError(Diagnostic{
+				Pos:     param.Pos(),
+				Message: fmt.Sprintf("required parameter %s after optional parameter", param.Value),
+				Got:     token.IDENT,
+			})
+			return false
+		}
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return false
+		}
 	}
+
+	return p.expectPeek(token.RPAREN)
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+func (p *Parser) parseMacroParameters() []*ast.Identifier {
 	params := []*ast.Identifier{}
 
 	if p.peekTokenIs(token.RPAREN) {
@@ -213,6 +368,59 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return params
 }
 
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		expression.CatchName = p.parseIdentifier().(*ast.Identifier)
+
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.addError(Diagnostic{
+			Pos:      p.peekToken.Pos,
+			Message:  fmt.Sprintf("expected catch or finally after try block, got %s instead", p.peekToken.Type),
+			Expected: token.CATCH,
+			Got:      p.peekToken.Type,
+		})
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	function := &ast.FunctionLiteral{
 		Token: p.curToken,
@@ -222,28 +430,155 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	function.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(function) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	// break and continue can't reach loops outside of the function
+	outerLoopDepth := p.loopDepth
+	p.loopDepth = 0
 	function.Body = p.parseBlockStatement()
+	p.loopDepth = outerLoopDepth
 
 	return function
 }
 
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	macro := &ast.MacroLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	macro.Parameters = p.parseMacroParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	outerLoopDepth := p.loopDepth
+	p.loopDepth = 0
+	macro.Body = p.parseBlockStatement()
+	p.loopDepth = outerLoopDepth
+
+	return macro
+}
+
+// Parses `import("path")`. The path must be a string literal so it's known
+// before the program runs.
+func (p *Parser) parseImportExpression() ast.Expression {
+	expression := &ast.ImportExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) || !p.expectPeek(token.STRING) {
+		return nil
+	}
+	expression.Path = p.curToken.Literal
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return expression
+}
+
+// Parses `match (subject) { pattern [if guard] => body, ... }`. Arms are
+// separated by commas, a trailing one is allowed. Warns if no arm matches
+// every value.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := &ast.MatchArm{Pattern: p.parseMatchPattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			arm.Guard = p.parseExpression(LOWEST)
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+
+		p.nextToken()
+		arm.Body = p.parseExpression(LOWEST)
+
+		expression.Arms = append(expression.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	// warned about here rather than by the engines: whether an arm is a
+	// catch-all only depends on the syntax, the parser is shared by both
+	// engines, and the evaluator would only see matches it happens to run.
+	// Matches spliced in by macros are not checked.
+	for _, arm := range expression.Arms {
+		if arm.IsCatchAll() {
+			return expression
+		}
+	}
+	p.warnings = append(p.warnings, Diagnostic{
+		Pos:     expression.Token.Pos,
+		Message: "match has no catch-all arm",
+	})
+
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{
 		Token:      p.curToken,
 		Statements: []ast.Statement{},
 	}
 
+	// the enclosing statement failed already and is skipped as a whole, the
+	// block's recovery mustn't end that
+	if p.panicking {
+		return block
+	}
+
 	p.nextToken()
 
+	p.blockDepth++
+	defer func() { p.blockDepth-- }()
+
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize()
+			if p.curTokenIs(token.RBRACE) {
+				break // recovery ended on the closing brace of this block
+			}
+		} else if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
@@ -349,9 +684,49 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return p.parseBigIntLiteral()
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+			Got:     p.curToken.Type,
+		})
+		return nil
+	}
+
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseBigIntLiteral() ast.Expression {
+	lit := &ast.BigIntLiteral{Token: p.curToken}
+
+	value, ok := new(big.Int).SetString(p.curToken.Literal, 0)
+	if !ok {
+		p.addError(Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+			Got:     p.curToken.Type,
+		})
+		return nil
+	}
+
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.addError(Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: fmt.Sprintf("could not parse %q as float", p.curToken.Literal),
+			Got:     p.curToken.Type,
+		})
 		return nil
 	}
 
@@ -363,22 +738,129 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// Formatted messages of all diagnostics, see Diagnostics.
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := make([]string, len(p.diagnostics))
+	for i, d := range p.diagnostics {
+		errors[i] = d.String()
+	}
+	return errors
+}
+
+func (p *Parser) Diagnostics() []Diagnostic {
+	return p.diagnostics
+}
+
+// Formatted messages of problems that don't prevent running the program, e.g.
+// a match that may not match its subject.
+func (p *Parser) Warnings() []string {
+	warnings := make([]string, len(p.warnings))
+	for i, d := range p.warnings {
+		warnings[i] = d.String()
+	}
+	return warnings
+}
+
+// Records a diagnostic. Only the first error of a statement is kept, the rest
+// are usually follow-ups of the same mistake until the parser synchronizes.
+func (p *Parser) addError(d Diagnostic) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+
+	repeats := 0
+	for _, seen := range p.diagnostics {
+		if seen.Pos == d.Pos {
+			return
+		}
+		if seen.Message == d.Message {
+			repeats++
+		}
+	}
+	if repeats >= MaxRepeatedDiagnostics {
+		p.dropped = append(p.dropped, d)
+		return
+	}
+
+	p.diagnostics = append(p.diagnostics, d)
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf(
-		"expected next token to be %s, got %s instead",
-		t,
-		p.peekToken.Type,
-	)
-	p.errors = append(p.errors, msg)
+	p.addError(Diagnostic{
+		Pos: p.peekToken.Pos,
+		Message: fmt.Sprintf(
+			"expected next token to be %s, got %s instead",
+			t,
+			p.peekToken.Type,
+		),
+		Expected: t,
+		Got:      p.peekToken.Type,
+	})
+}
+
+// Skips the rest of an erroneous statement so parsing resumes at the next
+// one. Stops on a `;`, or before a keyword starting a statement or the `}`
+// closing the current block. Delimiters opened while skipping are skipped up
+// to their closing one, so statements nested in them, e.g. in a function
+// literal argument, aren't mistaken for the next statement. Leaves curToken on
+// the last skipped token.
+func (p *Parser) synchronize() {
+	p.panicking = false
+
+	depth := 0 // delimiters opened while skipping
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET:
+			// closers of delimiters opened before the error are skipped
+			if depth > 0 {
+				depth--
+			}
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			} else if p.blockDepth > 0 {
+				return
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+
+		if depth == 0 {
+			switch p.peekToken.Type {
+			case token.LET, token.RETURN, token.WHILE, token.FOR,
+				token.BREAK, token.CONTINUE, token.THROW, token.EOF:
+				return
+			case token.RBRACE:
+				if p.blockDepth > 0 {
+					return
+				}
+			}
+		}
+
+		p.nextToken()
+	}
 }
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	// comments are trivia, collect them without ever parsing them
+	for p.peekToken.Type == token.COMMENT {
+		p.comments = append(p.comments, p.peekToken)
+		p.peekToken = p.l.NextToken()
+	}
+}
+
+// COMMENT tokens in source order. Only populated if the lexer preserves
+// comments.
+func (p *Parser) Comments() []token.Token {
+	return p.comments
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -387,12 +869,24 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 	for p.curToken.Type != token.EOF {
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize()
+		} else if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
 	}
 
+	if len(p.dropped) > 0 {
+		p.diagnostics = append(p.diagnostics, Diagnostic{
+			Pos: p.dropped[0].Pos,
+			Message: fmt.Sprintf(
+				"too many errors, %d repeated ones not shown",
+				len(p.dropped),
+			),
+		})
+	}
+
 	return program
 }
 
@@ -400,8 +894,18 @@ func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -410,18 +914,302 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
+	switch p.peekToken.Type {
+	case token.LBRACKET:
+		p.nextToken()
+		stmt.Pattern = p.parseArrayPattern(false)
+	case token.LBRACE:
+		p.nextToken()
+		stmt.Pattern = p.parseHashPattern(false)
+	default:
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if stmt.Name == nil && stmt.Pattern == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	// name function literals after their binding so they can refer to
+	// themselves
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok && stmt.Name != nil {
+		fl.Name = stmt.Name.Value
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// Parses `[a, b, ...rest]`, where the rest is optional and comes last. In a
+// match arm the elements may be any pattern, in a let statement only names.
+func (p *Parser) parseArrayPattern(match bool) ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = p.parseNamePattern(match)
+			break
+		}
+
+		element := p.parsePatternElement(match)
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+// Parses `{name, age}`. In a match arm keys may also be followed by a
+// pattern for their value, e.g. `{"kind": "circle", radius: r}`.
+func (p *Parser) parseHashPattern(match bool) ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		var pair ast.HashPatternPair
+
+		if match && p.peekTokenIs(token.STRING) {
+			p.nextToken()
+			pair.Key = p.curToken.Literal
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			pair.Value = p.parsePatternElement(match)
+		} else {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pair.Key = p.curToken.Literal
+			pair.Value = &ast.BindingPattern{
+				Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+			}
+			if match && p.peekTokenIs(token.COLON) {
+				p.nextToken()
+				pair.Value = p.parsePatternElement(match)
+			}
+		}
+
+		if pair.Value == nil {
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return pattern
+}
+
+// Parses the pattern after curToken: any pattern in a match arm, a name in a
+// let statement.
+func (p *Parser) parsePatternElement(match bool) ast.Pattern {
+	if match {
+		p.nextToken()
+		return p.parseMatchPattern()
+	}
+
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
+	return p.parseNamePattern(false)
+}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+// Parses the name in curToken. `_` is a wildcard in a match arm.
+func (p *Parser) parseNamePattern(match bool) ast.Pattern {
+	if match && p.curToken.Literal == "_" {
+		return &ast.WildcardPattern{Token: p.curToken}
+	}
+	return &ast.BindingPattern{
+		Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+	}
+}
 
-	if !p.expectPeek(token.ASSIGN) {
+// Parses the pattern of a match arm, starting at curToken.
+func (p *Parser) parseMatchPattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return p.parseNamePattern(true)
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		value := p.prefixParseFns[p.curToken.Type]()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Value: value}
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			p.peekError(token.INT)
+			return nil
+		}
+		return &ast.LiteralPattern{Value: p.parsePrefixExpression()}
+	case token.LBRACKET:
+		return p.parseArrayPattern(true)
+	case token.LBRACE:
+		return p.parseHashPattern(true)
+	default:
+		p.addError(Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: fmt.Sprintf("invalid pattern starting with %s", p.curToken.Type),
+			Got:     p.curToken.Type,
+		})
+		return nil
+	}
+}
+
+// Parses `export let ...`, which must be at the top level of the program.
+func (p *Parser) parseExportStatement() ast.Statement {
+	if p.blockDepth > 0 {
+		p.addError(Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: "export outside of top level",
+			Got:     p.curToken.Type,
+		})
+		return nil
+	}
+
+	if !p.expectPeek(token.LET) {
+		return nil
+	}
+
+	stmt := p.parseLetStatement()
+	if stmt == nil {
+		return nil
+	}
+	stmt.Exported = true
+
+	return stmt
+}
+
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
 	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
 
+	stmt.Body = p.parseLoopBody()
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Names = append(stmt.Names, p.parseIdentifier().(*ast.Identifier))
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Names = append(stmt.Names, p.parseIdentifier().(*ast.Identifier))
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	return stmt
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	return p.parseBlockStatement()
+}
+
+// Parses a break or continue statement, which must be inside a loop.
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	var stmt ast.Statement
+	if p.curTokenIs(token.BREAK) {
+		stmt = &ast.BreakStatement{Token: p.curToken}
+	} else {
+		stmt = &ast.ContinueStatement{Token: p.curToken}
+	}
+
+	if p.loopDepth == 0 {
+		p.addError(Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: fmt.Sprintf("%s outside of loop", p.curToken.Literal),
+			Got:     p.curToken.Type,
+		})
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
 
 	if p.peekTokenIs(token.SEMICOLON) {
@@ -446,10 +1234,8 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 }
 
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	stmt := &ast.ExpressionStatement{
-		Token:      p.curToken,
-		Expression: p.parseExpression(LOWEST),
-	}
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -459,8 +1245,11 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(Diagnostic{
+		Pos:     p.curToken.Pos,
+		Message: fmt.Sprintf("no prefix parse function for %s found", t),
+		Got:     t,
+	})
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/tjapit/monkey/src/ast"
 	"github.com/tjapit/monkey/src/lexer"
+	"github.com/tjapit/monkey/src/token"
 )
 
 func TestLetStatement(t *testing.T) {
@@ -241,6 +242,59 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntLiteralExpression(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "9223372036854775808;", "9223372036854775808"},
+		{"Test 2", "123456789012345678901234567890;", "123456789012345678901234567890"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			stmt := program.Statements[0].(*ast.ExpressionStatement)
+			literal, ok := stmt.Expression.(*ast.BigIntLiteral)
+			if !ok {
+				t.Fatalf("exp not *ast.BigIntLiteral. got=%T", stmt.Expression)
+			}
+			if literal.Value.String() != tC.expected {
+				t.Errorf("literal.Value not %s. got =%s", tC.expected, literal.Value)
+			}
+		})
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "2.5e-1;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != 0.25 {
+		t.Errorf("literal.Value not %g. got =%g", 0.25, literal.Value)
+	}
+	if literal.TokenLiteral() != "2.5e-1" {
+		t.Errorf(
+			"literal.TokenLiteral not %s. got =%s",
+			"2.5e-1",
+			literal.TokenLiteral(),
+		)
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -479,6 +533,11 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"true == true;", true, "==", true},
 		{"true != false;", true, "!=", false},
 		{"false == false;", false, "==", false},
+		{"5 % 5;", 5, "%", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"true && false;", true, "&&", false},
+		{"true || false;", true, "||", false},
 	}
 
 	for _, tt := range infixTests {
@@ -628,6 +687,22 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a == b && c != d || !e",
+			"(((a == b) && (c != d)) || (!e))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -776,183 +851,126 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
-func TestFunctionLiteralParsing(t *testing.T) {
-	input := `fn(x, y) { x + y; }`
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { x += 1; continue; }`
 
 	l := lexer.New(input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
-	if len(program.Statements) != 1 {
-		t.Fatalf(
-			"program does not contain %d statements. got=%d",
-			1,
-			len(program.Statements),
-		)
-	}
-
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
 	if !ok {
 		t.Fatalf(
-			"program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			"program.Statements[0] is not ast.WhileStatement. got=%T",
 			program.Statements[0],
 		)
 	}
 
-	function, ok := stmt.Expression.(*ast.FunctionLiteral)
-	if !ok {
-		t.Fatalf(
-			"stmt.Expression is not ast.FunctionLiteral. got=%T",
-			stmt.Expression,
-		)
-	}
-
-	if len(function.Parameters) != 2 {
-		t.Fatalf(
-			"function literal parameters wrong. want=%d, got =%d",
-			2,
-			len(function.Parameters),
-		)
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
 	}
 
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
-
-	if len(function.Body.Statements) != 1 {
-		t.Fatalf(
-			"function.Body.Statements wrong. want=%d, got =%d",
-			1,
-			len(function.Body.Statements),
-		)
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d", len(stmt.Body.Statements))
 	}
 
-	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf(
-			"body is not *ast.ExpressionStatement. got=%T",
-			bodyStmt,
+	if _, ok := stmt.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf(
+			"stmt.Body.Statements[1] is not ast.ContinueStatement. got=%T",
+			stmt.Body.Statements[1],
 		)
 	}
-	if !testInfixExpression(t, bodyStmt.Expression, "x", "+", "y") {
-		return
-	}
 }
 
-func TestFunctionParameters(t *testing.T) {
-	tests := []struct {
-		input     string
-		expParams []string
+func TestForStatement(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		input         string
+		expectedNames []string
+		expected      string
 	}{
-		{
-			input:     "fn() {}",
-			expParams: []string{},
-		}, {
-			input:     "fn(x) {}",
-			expParams: []string{"x"},
-		}, {
-			input:     "fn(x, y, z) {}",
-			expParams: []string{"x", "y", "z"},
-		},
+		{"Test 1", "for (x in arr) { x; }", []string{"x"}, "for (x in arr) x"},
+		{"Test 2", "for (k, v in {}) { break; }", []string{"k", "v"}, "for (k, v in {}) break;"},
+		{"Test 3", "for (c in \"abc\" + s) {}", []string{"c"}, "for (c in (abc + s)) "},
 	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
 
-	for _, tt := range tests {
-		l := lexer.New(tt.input)
-		p := New(l)
-		program := p.ParseProgram()
-		checkParserErrors(t, p)
-
-		stmt := program.Statements[0].(*ast.ExpressionStatement)
-		function := stmt.Expression.(*ast.FunctionLiteral)
+			stmt, ok := program.Statements[0].(*ast.ForStatement)
+			if !ok {
+				t.Fatalf(
+					"program.Statements[0] is not ast.ForStatement. got=%T",
+					program.Statements[0],
+				)
+			}
 
-		if len(function.Parameters) != len(tt.expParams) {
-			t.Errorf(
-				"length parameters wrong. want=%d, got =%d",
-				len(tt.expParams),
-				len(function.Parameters),
-			)
-		}
+			if len(stmt.Names) != len(tC.expectedNames) {
+				t.Fatalf(
+					"wrong number of names. want=%d, got =%d",
+					len(tC.expectedNames),
+					len(stmt.Names),
+				)
+			}
+			for i, name := range tC.expectedNames {
+				testIdentifier(t, stmt.Names[i], name)
+			}
 
-		for i, ident := range tt.expParams {
-			testLiteralExpression(t, function.Parameters[i], ident)
-		}
+			if program.String() != tC.expected {
+				t.Errorf("expected=%q, got =%q", tC.expected, program.String())
+			}
+		})
 	}
 }
 
-func TestCallExpressionParsing(t *testing.T) {
-	input := "add(1, 2 * 3, 4 + 5);"
-
-	l := lexer.New(input)
-	p := New(l)
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
-
-	if len(program.Statements) != 1 {
-		t.Fatalf(
-			"program does not contain %d statements. got=%d",
-			1,
-			len(program.Statements),
-		)
-	}
-
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf(
-			"program.Statements[0] is not ast.ExpressionStatement. got=%T",
-			program.Statements[0],
-		)
-	}
-
-	exp, ok := stmt.Expression.(*ast.CallExpression)
-	if !ok {
-		t.Fatalf(
-			"stmt.Expression is not ast.CallExpression. got=%T",
-			stmt.Expression,
-		)
+func TestLoopParsingErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "break;", "1:1: break outside of loop"},
+		{"Test 2", "if (x) { continue; }", "1:10: continue outside of loop"},
+		{"Test 3", "while (x) { fn() { break; } }", "1:20: break outside of loop"},
+		{"Test 4", "for (x in y z) {}", "1:13: expected next token to be ), got IDENT instead"},
 	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			p.ParseProgram()
 
-	if !testIdentifier(t, exp.Function, "add") {
-		return
-	}
+			errors := p.Errors()
+			if len(errors) != 1 {
+				t.Fatalf("expected 1 parser error, got =%d: %v", len(errors), errors)
+			}
 
-	if len(exp.Arguments) != 3 {
-		t.Fatalf(
-			"incorrect length of arguments. want=%d, got =%d",
-			3,
-			len(exp.Arguments),
-		)
+			if errors[0] != tC.expected {
+				t.Errorf(
+					"wrong error message. want=%q, got =%q",
+					tC.expected,
+					errors[0],
+				)
+			}
+		})
 	}
-
-	testLiteralExpression(t, exp.Arguments[0], 1)
-	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
-	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
 
-func TestCallExpressionArgumentParsing(t *testing.T) {
+func TestTryExpression(t *testing.T) {
 	testCases := []struct {
-		desc          string
-		input         string
-		expectedIdent string
-		expectedArgs  []string
+		desc              string
+		input             string
+		expectedCatchName string
+		expected          string
 	}{
-		{
-			desc:          "0 args",
-			input:         "add();",
-			expectedIdent: "add",
-			expectedArgs:  []string{},
-		},
-		{
-			desc:          "1 args",
-			input:         "add(1);",
-			expectedIdent: "add",
-			expectedArgs:  []string{"1"},
-		},
-		{
-			desc:          ">1 args",
-			input:         "add(1, 2 * 3, 4 + 5);",
-			expectedIdent: "add",
-			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
-		},
+		{"Test 1", "try { f(); } catch (e) { e; }", "e", "try f() catch (e) e"},
+		{"Test 2", "try { x } finally { y }", "", "try x finally y"},
+		{"Test 3", "let r = try { 1 } catch (err) { 2 } finally { 3 };", "err", "let r = try 1 catch (err) 2 finally 3;"},
+		{"Test 4", "try { throw error(\"no\"); } catch (e) {}", "e", "try throw error(no); catch (e) "},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -961,60 +979,776 @@ func TestCallExpressionArgumentParsing(t *testing.T) {
 			program := p.ParseProgram()
 			checkParserErrors(t, p)
 
-			stmt := program.Statements[0].(*ast.ExpressionStatement)
-			exp, ok := stmt.Expression.(*ast.CallExpression)
-			if !ok {
-				t.Fatalf(
-					"stmt.Expression is not ast.CallExpression. got=%T",
-					stmt.Expression,
-				)
+			if program.String() != tC.expected {
+				t.Errorf("expected=%q, got =%q", tC.expected, program.String())
 			}
 
-			if !testIdentifier(t, exp.Function, tC.expectedIdent) {
-				return
+			var expression ast.Expression
+			switch stmt := program.Statements[0].(type) {
+			case *ast.ExpressionStatement:
+				expression = stmt.Expression
+			case *ast.LetStatement:
+				expression = stmt.Value
 			}
 
-			if len(exp.Arguments) != len(tC.expectedArgs) {
-				t.Fatalf(
-					"incorrect number of arguments. want=%d, got =%d",
-					len(tC.expectedArgs),
-					len(exp.Arguments),
-				)
+			tryExp, ok := expression.(*ast.TryExpression)
+			if !ok {
+				t.Fatalf("expression is not ast.TryExpression. got=%T", expression)
 			}
 
-			for i, expectedArg := range tC.expectedArgs {
-				if exp.Arguments[i].String() != expectedArg {
-					t.Errorf(
-						"argument #%d wrong. want=%q, got =%q",
-						i,
-						expectedArg,
-						exp.Arguments[i].String(),
-					)
+			if tC.expectedCatchName == "" {
+				if tryExp.CatchName != nil || tryExp.Catch != nil {
+					t.Errorf("unexpected catch clause: %s", tryExp.CatchName)
 				}
+				return
 			}
+			testIdentifier(t, tryExp.CatchName, tC.expectedCatchName)
 		})
 	}
 }
 
-func TestStringLiteralExpression(t *testing.T) {
-	input := `"hello world";`
-	expected := "hello world"
+func TestThrowStatement(t *testing.T) {
+	input := `throw x + 1;`
 
 	l := lexer.New(input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
-	stmt := program.Statements[0].(*ast.ExpressionStatement)
-	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
 	if !ok {
-		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		t.Fatalf(
+			"program.Statements[0] is not ast.ThrowStatement. got=%T",
+			program.Statements[0],
+		)
 	}
 
-	if literal.Value != expected {
-		t.Errorf(
-			"literal.Value wrong. want=%q, got =%q",
-			expected,
+	testInfixExpression(t, stmt.Value, "x", "+", 1)
+}
+
+func TestTryParsingErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "try { x; }", "1:11: expected catch or finally after try block, got EOF instead"},
+		{"Test 2", "try { x; } catch { y; }", "1:18: expected next token to be (, got { instead"},
+		{"Test 3", "try { x; } catch (1) { y; }", "1:19: expected next token to be IDENT, got INT instead"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			p.ParseProgram()
+
+			errors := p.Errors()
+			if len(errors) == 0 {
+				t.Fatalf("expected parser errors, got none")
+			}
+
+			if errors[0] != tC.expected {
+				t.Errorf(
+					"wrong error message. want=%q, got =%q",
+					tC.expected,
+					errors[0],
+				)
+			}
+		})
+	}
+}
+
+func TestImportExpression(t *testing.T) {
+	input := `let m = import("lib/math.mk");`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not ast.LetStatement. got=%T",
+			program.Statements[0],
+		)
+	}
+
+	imp, ok := stmt.Value.(*ast.ImportExpression)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.ImportExpression. got=%T", stmt.Value)
+	}
+
+	if imp.Path != "lib/math.mk" {
+		t.Errorf("imp.Path not %q. got=%q", "lib/math.mk", imp.Path)
+	}
+	if imp.String() != `import("lib/math.mk")` {
+		t.Errorf("imp.String() wrong. got=%q", imp.String())
+	}
+}
+
+func TestExportStatement(t *testing.T) {
+	input := `
+export let x = 5;
+let y = 6;
+`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf(
+			"program.Statements does not contain %d statements. got=%d",
+			2,
+			len(program.Statements),
+		)
+	}
+
+	testCases := []struct {
+		desc     string
+		name     string
+		exported bool
+	}{
+		{"Test 1", "x", true},
+		{"Test 2", "y", false},
+	}
+	for i, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			stmt := program.Statements[i]
+			if !testLetStatement(t, stmt, tC.name) {
+				return
+			}
+
+			if stmt.(*ast.LetStatement).Exported != tC.exported {
+				t.Errorf(
+					"stmt.Exported not %t. got=%t",
+					tC.exported,
+					stmt.(*ast.LetStatement).Exported,
+				)
+			}
+		})
+	}
+
+	if program.Statements[0].String() != "export let x = 5;" {
+		t.Errorf("String() wrong. got=%q", program.Statements[0].String())
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string   // String() of the statement
+		names    []string // names bound by the pattern
+	}{
+		{"Test 1", "let [a, b] = arr;", "let [a, b] = arr;", []string{"a", "b"}},
+		{"Test 2", "let [a, ...rest] = arr;", "let [a, ...rest] = arr;", []string{"a", "rest"}},
+		{"Test 3", "let [...all] = arr;", "let [...all] = arr;", []string{"all"}},
+		{"Test 4", "let [] = arr;", "let [] = arr;", []string{}},
+		{"Test 5", "let {name, age} = person;", "let {name, age} = person;", []string{"name", "age"}},
+		{"Test 6", "let {name,} = person;", "let {name} = person;", []string{"name"}},
+		{"Test 7", "export let [x] = [1];", "export let [x] = [1];", []string{"x"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			stmt, ok := program.Statements[0].(*ast.LetStatement)
+			if !ok {
+				t.Fatalf(
+					"program.Statements[0] is not ast.LetStatement. got=%T",
+					program.Statements[0],
+				)
+			}
+			if stmt.Pattern == nil {
+				t.Fatalf("stmt.Pattern is nil")
+			}
+
+			if stmt.String() != tC.expected {
+				t.Errorf("String() wrong. want=%q, got =%q", tC.expected, stmt.String())
+			}
+
+			names := stmt.Names()
+			if len(names) != len(tC.names) {
+				t.Fatalf(
+					"wrong number of names. want=%d, got =%d",
+					len(tC.names),
+					len(names),
+				)
+			}
+			for i, name := range tC.names {
+				testIdentifier(t, names[i], name)
+			}
+		})
+	}
+}
+
+func TestDestructuringParsingErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "let [a, 1] = arr;", "1:9: expected next token to be IDENT, got INT instead"},
+		{"Test 2", "let [...rest, a] = arr;", "1:13: expected next token to be ], got , instead"},
+		{"Test 3", "let [a b] = arr;", "1:8: expected next token to be ,, got IDENT instead"},
+		{"Test 4", `let {"name"} = person;`, "1:6: expected next token to be IDENT, got STRING instead"},
+		{"Test 5", "let {a: b} = person;", "1:7: expected next token to be ,, got : instead"},
+		{"Test 6", "let [a] arr;", "1:9: expected next token to be =, got IDENT instead"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			p.ParseProgram()
+
+			errors := p.Errors()
+			if len(errors) == 0 {
+				t.Fatalf("expected parser errors, got none")
+			}
+
+			if errors[0] != tC.expected {
+				t.Errorf(
+					"wrong error message. want=%q, got =%q",
+					tC.expected,
+					errors[0],
+				)
+			}
+		})
+	}
+}
+
+func TestModuleParsingErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", `import "lib.mk"`, "1:8: expected next token to be (, got STRING instead"},
+		{"Test 2", `import(path)`, "1:8: expected next token to be STRING, got IDENT instead"},
+		{"Test 3", `export x = 1;`, "1:8: expected next token to be LET, got IDENT instead"},
+		{"Test 4", `fn() { export let x = 1; }`, "1:8: export outside of top level"},
+		{"Test 5", `if (true) { export let x = 1; }`, "1:13: export outside of top level"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			p.ParseProgram()
+
+			errors := p.Errors()
+			if len(errors) == 0 {
+				t.Fatalf("expected parser errors, got none")
+			}
+
+			if errors[0] != tC.expected {
+				t.Errorf(
+					"wrong error message. want=%q, got =%q",
+					tC.expected,
+					errors[0],
+				)
+			}
+		})
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string   // String() of the expression
+		names    []string // names bound by the first arm's pattern
+	}{
+		{
+			"Test 1",
+			`match (x) { 1 => "one", _ => "other" }`,
+			`match (x) { 1 => one, _ => other }`,
+			[]string{},
+		},
+		{
+			"Test 2",
+			`match (x) { -1 => a, 2.5 => b, "s" => c, true => d, y => y, }`,
+			`match (x) { (-1) => a, 2.5 => b, "s" => c, true => d, y => y }`,
+			[]string{},
+		},
+		{
+			"Test 3",
+			`match (x) { [a, _, ...rest] => a, [1, [b]] => b }`,
+			`match (x) { [a, _, ...rest] => a, [1, [b]] => b }`,
+			[]string{"a", "rest"},
+		},
+		{
+			"Test 4",
+			`match (x) { {"kind": "circle", r} => r, {size: [w, h]} => w * h }`,
+			`match (x) { {"kind": "circle", r} => r, {"size": [w, h]} => (w * h) }`,
+			[]string{"r"},
+		},
+		{
+			"Test 5",
+			`match (x) { n if n > 0 => n, _ => 0 }`,
+			`match (x) { n if (n > 0) => n, _ => 0 }`,
+			[]string{"n"},
+		},
+		{
+			"Test 6",
+			`match (f(x)) { [...xs] => match (xs) { _ => 1 } }`,
+			`match (f(x)) { [...xs] => match (xs) { _ => 1 } }`,
+			[]string{"xs"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+			if !ok {
+				t.Fatalf(
+					"program.Statements[0] is not ast.ExpressionStatement. got=%T",
+					program.Statements[0],
+				)
+			}
+
+			exp, ok := stmt.Expression.(*ast.MatchExpression)
+			if !ok {
+				t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+			}
+
+			if exp.String() != tC.expected {
+				t.Errorf("String() wrong. want=%q, got =%q", tC.expected, exp.String())
+			}
+
+			names := exp.Arms[0].Pattern.Names()
+			if len(names) != len(tC.names) {
+				t.Fatalf(
+					"wrong number of names. want=%d, got =%d",
+					len(tC.names),
+					len(names),
+				)
+			}
+			for i, name := range tC.names {
+				testIdentifier(t, names[i], name)
+			}
+		})
+	}
+}
+
+func TestMatchParsingErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", `match x { _ => 1 }`, "1:7: expected next token to be (, got IDENT instead"},
+		{"Test 2", `match (x) { 1 2 }`, "1:15: expected next token to be =>, got INT instead"},
+		{"Test 3", `match (x) { 1 => a 2 => b }`, "1:20: expected next token to be ,, got INT instead"},
+		{"Test 4", `match (x) { a + 1 => a }`, "1:15: expected next token to be =>, got + instead"},
+		{"Test 5", `match (x) { fn() {} => 1 }`, "1:13: invalid pattern starting with FUNCTION"},
+		{"Test 6", `match (x) { -a => 1 }`, "1:14: expected next token to be INT, got IDENT instead"},
+		{"Test 7", `match (x) { [...1] => 1 }`, "1:17: expected next token to be IDENT, got INT instead"},
+		{"Test 8", `match (x) { {1: a} => 1 }`, "1:14: expected next token to be IDENT, got INT instead"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			p.ParseProgram()
+
+			errors := p.Errors()
+			if len(errors) == 0 {
+				t.Fatalf("expected parser errors, got none")
+			}
+
+			if errors[0] != tC.expected {
+				t.Errorf(
+					"wrong error message. want=%q, got =%q",
+					tC.expected,
+					errors[0],
+				)
+			}
+		})
+	}
+}
+
+func TestMatchWarnings(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected []string
+	}{
+		{"Test 1", `match (x) { 1 => a, _ => b }`, []string{}},
+		{"Test 2", `match (x) { 1 => a, y => y }`, []string{}},
+		{"Test 3", `match (x) { 1 => a }`, []string{"1:1: match has no catch-all arm"}},
+		{"Test 4", `match (x) { y if y => a }`, []string{"1:1: match has no catch-all arm"}},
+		{"Test 5", `match (x) { [_] => a, {} => b }`, []string{"1:1: match has no catch-all arm"}},
+		{
+			"Test 6",
+			"let f = fn(x) {\n  match (x) { _ => match (x) {} }\n};",
+			[]string{"2:20: match has no catch-all arm"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			p.ParseProgram()
+			checkParserErrors(t, p)
+
+			warnings := p.Warnings()
+			if len(warnings) != len(tC.expected) {
+				t.Fatalf(
+					"wrong number of warnings. want=%d, got =%d (%q)",
+					len(tC.expected),
+					len(warnings),
+					warnings,
+				)
+			}
+			for i, want := range tC.expected {
+				if warnings[i] != want {
+					t.Errorf("wrong warning. want=%q, got =%q", want, warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"program does not contain %d statements. got=%d",
+			1,
+			len(program.Statements),
+		)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0],
+		)
+	}
+
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf(
+			"stmt.Expression is not ast.FunctionLiteral. got=%T",
+			stmt.Expression,
+		)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf(
+			"function literal parameters wrong. want=%d, got =%d",
+			2,
+			len(function.Parameters),
+		)
+	}
+
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf(
+			"function.Body.Statements wrong. want=%d, got =%d",
+			1,
+			len(function.Body.Statements),
+		)
+	}
+
+	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"body is not *ast.ExpressionStatement. got=%T",
+			bodyStmt,
+		)
+	}
+	if !testInfixExpression(t, bodyStmt.Expression, "x", "+", "y") {
+		return
+	}
+}
+
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input     string
+		expParams []string
+	}{
+		{
+			input:     "fn() {}",
+			expParams: []string{},
+		}, {
+			input:     "fn(x) {}",
+			expParams: []string{"x"},
+		}, {
+			input:     "fn(x, y, z) {}",
+			expParams: []string{"x", "y", "z"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expParams) {
+			t.Errorf(
+				"length parameters wrong. want=%d, got =%d",
+				len(tt.expParams),
+				len(function.Parameters),
+			)
+		}
+
+		for i, ident := range tt.expParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string // String() of the function
+		defaults int    // number of parameters with default values
+		rest     string // name of the rest parameter, empty if none
+	}{
+		{"Test 1", "fn(a, b = 10) {}", "fn(a, b = 10) ", 1, ""},
+		{"Test 2", "fn(a = 1, b = a * 2) {}", "fn(a = 1, b = (a * 2)) ", 2, ""},
+		{"Test 3", "fn(...rest) {}", "fn(...rest) ", 0, "rest"},
+		{"Test 4", "fn(a, b = 10, ...rest) {}", "fn(a, b = 10, ...rest) ", 1, "rest"},
+		{"Test 5", "fn(a, b,) {}", "fn(a, b) ", 0, ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			stmt := program.Statements[0].(*ast.ExpressionStatement)
+			function, ok := stmt.Expression.(*ast.FunctionLiteral)
+			if !ok {
+				t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+			}
+
+			if function.String() != tC.expected {
+				t.Errorf("String() wrong. want=%q, got =%q", tC.expected, function.String())
+			}
+
+			if len(function.Defaults) != tC.defaults {
+				t.Errorf(
+					"wrong number of defaults. want=%d, got =%d",
+					tC.defaults,
+					len(function.Defaults),
+				)
+			}
+
+			if tC.rest == "" {
+				if function.Rest != nil {
+					t.Errorf("function.Rest is not nil. got=%q", function.Rest)
+				}
+				return
+			}
+			testIdentifier(t, function.Rest, tC.rest)
+		})
+	}
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "fn(a = 1, b) {}", "1:11: required parameter b after optional parameter"},
+		{"Test 2", "fn(...rest, a) {}", "1:11: expected next token to be ), got , instead"},
+		{"Test 3", "fn(...1) {}", "1:7: expected next token to be IDENT, got INT instead"},
+		{"Test 4", "fn(1) {}", "1:4: expected next token to be IDENT, got INT instead"},
+		{"Test 5", "fn(a b) {}", "1:6: expected next token to be ,, got IDENT instead"},
+		{"Test 6", "f(...)", "1:6: no prefix parse function for ) found"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			p.ParseProgram()
+
+			errors := p.Errors()
+			if len(errors) == 0 {
+				t.Fatalf("expected parser errors, got none")
+			}
+
+			if errors[0] != tC.expected {
+				t.Errorf(
+					"wrong error message. want=%q, got =%q",
+					tC.expected,
+					errors[0],
+				)
+			}
+		})
+	}
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+	input := "add(1, 2 * 3, 4 + 5);"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"program does not contain %d statements. got=%d",
+			1,
+			len(program.Statements),
+		)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0],
+		)
+	}
+
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf(
+			"stmt.Expression is not ast.CallExpression. got=%T",
+			stmt.Expression,
+		)
+	}
+
+	if !testIdentifier(t, exp.Function, "add") {
+		return
+	}
+
+	if len(exp.Arguments) != 3 {
+		t.Fatalf(
+			"incorrect length of arguments. want=%d, got =%d",
+			3,
+			len(exp.Arguments),
+		)
+	}
+
+	testLiteralExpression(t, exp.Arguments[0], 1)
+	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
+	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+}
+
+func TestCallExpressionArgumentParsing(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		input         string
+		expectedIdent string
+		expectedArgs  []string
+	}{
+		{
+			desc:          "0 args",
+			input:         "add();",
+			expectedIdent: "add",
+			expectedArgs:  []string{},
+		},
+		{
+			desc:          "1 args",
+			input:         "add(1);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"1"},
+		},
+		{
+			desc:          ">1 args",
+			input:         "add(1, 2 * 3, 4 + 5);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
+		},
+		{
+			desc:          "spread args",
+			input:         "add(...xs, 1, ...[2, 3]);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"...xs", "1", "...[2, 3]"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			stmt := program.Statements[0].(*ast.ExpressionStatement)
+			exp, ok := stmt.Expression.(*ast.CallExpression)
+			if !ok {
+				t.Fatalf(
+					"stmt.Expression is not ast.CallExpression. got=%T",
+					stmt.Expression,
+				)
+			}
+
+			if !testIdentifier(t, exp.Function, tC.expectedIdent) {
+				return
+			}
+
+			if len(exp.Arguments) != len(tC.expectedArgs) {
+				t.Fatalf(
+					"incorrect number of arguments. want=%d, got =%d",
+					len(tC.expectedArgs),
+					len(exp.Arguments),
+				)
+			}
+
+			for i, expectedArg := range tC.expectedArgs {
+				if exp.Arguments[i].String() != expectedArg {
+					t.Errorf(
+						"argument #%d wrong. want=%q, got =%q",
+						i,
+						expectedArg,
+						exp.Arguments[i].String(),
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello world";`
+	expected := "hello world"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != expected {
+		t.Errorf(
+			"literal.Value wrong. want=%q, got =%q",
+			expected,
 			literal.Value,
 		)
 	}
@@ -1047,6 +1781,41 @@ func TestParsingArrayLiterals(t *testing.T) {
 	testInfixExpression(t, array.Elements[2], 3, "+", 3)
 }
 
+func TestParsingAssignExpressions(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "x = 5;", "(x = 5)"},
+		{"Test 2", "x += y * 2;", "(x += (y * 2))"},
+		{"Test 3", "x -= 1;", "(x -= 1)"},
+		{"Test 4", "x *= 1;", "(x *= 1)"},
+		{"Test 5", "x /= 1;", "(x /= 1)"},
+		{"Test 6", "x %= 1;", "(x %= 1)"},
+		{"Test 7", "a = b = c;", "(a = (b = c))"},
+		{"Test 8", "arr[i + 1] = x || y;", "((arr[(i + 1)]) = (x || y))"},
+		{"Test 9", `h["k"] += 1;`, "((h[k]) += 1)"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			stmt := program.Statements[0].(*ast.ExpressionStatement)
+			if _, ok := stmt.Expression.(*ast.AssignExpression); !ok {
+				t.Fatalf("exp not *ast.AssignExpression. got=%T", stmt.Expression)
+			}
+
+			if actual := program.String(); actual != tC.expected {
+				t.Errorf("expected=%q, got =%q", tC.expected, actual)
+			}
+		})
+	}
+}
+
 func TestParsingIndexExpressions(t *testing.T) {
 	input := "myArray[1 + 1]"
 
@@ -1166,3 +1935,361 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		testFunc(value)
 	}
 }
+
+func TestFunctionLiteralWithName(t *testing.T) {
+	input := `let myFunction = fn() { };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"program.Statements does not contain %d statements. got=%d",
+			1,
+			len(program.Statements),
+		)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not ast.LetStatement. got=%T",
+			program.Statements[0],
+		)
+	}
+
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+
+	if function.Name != "myFunction" {
+		t.Errorf(
+			"function literal name wrong. want=%q, got =%q",
+			"myFunction",
+			function.Name,
+		)
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"program.Statements does not contain %d statements. got=%d",
+			1,
+			len(program.Statements),
+		)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0],
+		)
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf(
+			"macro literal parameters wrong. want 2, got =%d",
+			len(macro.Parameters),
+		)
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf(
+			"macro.Body.Statements has not 1 statements. got=%d",
+			len(macro.Body.Statements),
+		)
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"macro body stmt is not ast.ExpressionStatement. got=%T",
+			macro.Body.Statements[0],
+		)
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{
+			"Missing identifier",
+			"let = 5;",
+			"1:5: expected next token to be IDENT, got = instead",
+		},
+		{
+			"No prefix parse function",
+			"let x = 1;\n  let y = );",
+			"2:11: no prefix parse function for ) found",
+		},
+		{
+			"Invalid assignment target",
+			"1 + 2 = 3;",
+			"1:7: invalid assignment target: (1 + 2)",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			p.ParseProgram()
+
+			errors := p.Errors()
+			if len(errors) == 0 {
+				t.Fatalf("expected parser errors, got none")
+			}
+
+			if errors[0] != tC.expected {
+				t.Errorf(
+					"wrong error message. want=%q, got =%q",
+					tC.expected,
+					errors[0],
+				)
+			}
+		})
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := "let x = 1;\nx + y;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[1].(*ast.ExpressionStatement)
+	infix, ok := stmt.Expression.(*ast.InfixExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.InfixExpression. got=%T", stmt.Expression)
+	}
+
+	nodes := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{program, "1:1"},
+		{stmt, "2:1"},
+		{infix, "2:3"},
+		{infix.Right, "2:5"},
+	}
+
+	for _, n := range nodes {
+		if n.node.Pos().String() != n.expected {
+			t.Errorf(
+				"wrong position for %q. want=%s, got =%s",
+				n.node.String(),
+				n.expected,
+				n.node.Pos(),
+			)
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		input              string
+		expectedErrors     []string
+		expectedStatements string
+	}{
+		{
+			"One error per statement",
+			"let = 1; let y = 2; let 5; x + ; y",
+			[]string{
+				"1:5: expected next token to be IDENT, got = instead",
+				"1:25: expected next token to be IDENT, got INT instead",
+				"1:32: no prefix parse function for ; found",
+			},
+			"let y = 2;y",
+		},
+		{
+			"Recover inside block",
+			"let f = fn() { let = 1; let z = 3; z }; let y = 2;",
+			[]string{"1:20: expected next token to be IDENT, got = instead"},
+			"let f = fn<f>() let z = 3;z;let y = 2;",
+		},
+		{
+			"Recover at closing brace",
+			"let f = fn(x) { x + }; f(1)",
+			[]string{"1:21: no prefix parse function for } found"},
+			"let f = fn<f>(x) f(1);",
+		},
+		{
+			"Synchronize on let",
+			"if (x { 1 } let a = 1;",
+			[]string{"1:7: expected next token to be ), got { instead"},
+			"let a = 1;",
+		},
+		{
+			"No cascade",
+			"))))))))",
+			[]string{"1:1: no prefix parse function for ) found"},
+			"",
+		},
+		{
+			"Skip nested statements",
+			"quote(x += 1), quote(while (x) { break; })",
+			[]string{"1:14: no prefix parse function for , found"},
+			"quote((x += 1))",
+		},
+		{
+			"Skip to enclosing closer",
+			"let a = [(2 +), fn() { let c = 1; }]; let b = 2;",
+			[]string{"1:14: no prefix parse function for ) found"},
+			"let b = 2;",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			l := lexer.New(tC.input)
+			p := New(l)
+			program := p.ParseProgram()
+
+			errors := p.Errors()
+			if len(errors) != len(tC.expectedErrors) {
+				t.Fatalf(
+					"wrong number of errors. want=%d, got =%d (%q)",
+					len(tC.expectedErrors),
+					len(errors),
+					errors,
+				)
+			}
+
+			for i, want := range tC.expectedErrors {
+				if errors[i] != want {
+					t.Errorf("errors[%d] wrong. want=%q, got =%q", i, want, errors[i])
+				}
+			}
+
+			if program.String() != tC.expectedStatements {
+				t.Errorf(
+					"wrong statements. want=%q, got =%q",
+					tC.expectedStatements,
+					program.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	l := lexer.New("let x 5;")
+	p := New(l)
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("wrong number of diagnostics. want=1, got =%d", len(diagnostics))
+	}
+
+	d := diagnostics[0]
+	if d.Pos.Line != 1 || d.Pos.Column != 7 {
+		t.Errorf("wrong position. want=1:7, got =%s", d.Pos)
+	}
+	if d.Expected != token.ASSIGN {
+		t.Errorf("wrong expected token. want=%q, got =%q", token.ASSIGN, d.Expected)
+	}
+	if d.Got != token.INT {
+		t.Errorf("wrong got token. want=%q, got =%q", token.INT, d.Got)
+	}
+}
+
+func TestRepeatedDiagnostics(t *testing.T) {
+	input := "let 1; let 2; let ; let 3; let 4; let 5;"
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	expected := []string{
+		"1:5: expected next token to be IDENT, got INT instead",
+		"1:12: expected next token to be IDENT, got INT instead",
+		"1:19: expected next token to be IDENT, got ; instead",
+		"1:25: expected next token to be IDENT, got INT instead",
+		"1:32: too many errors, 2 repeated ones not shown",
+	}
+
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. want=%d, got =%d (%q)", len(expected), len(errors), errors)
+	}
+	for i, want := range expected {
+		if errors[i] != want {
+			t.Errorf("errors[%d] wrong. want=%q, got =%q", i, want, errors[i])
+		}
+	}
+}
+
+func TestIllegalTokenError(t *testing.T) {
+	l := lexer.New("let s = \"abc;\nlet y = 1;")
+	p := New(l)
+	p.ParseProgram()
+
+	expected := []string{"1:9: illegal token: unterminated string literal"}
+
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. want=%d, got =%d (%q)", len(expected), len(errors), errors)
+	}
+	if errors[0] != expected[0] {
+		t.Errorf("wrong error. want=%q, got =%q", expected[0], errors[0])
+	}
+}
+
+func TestParsingWithPreservedComments(t *testing.T) {
+	input := `// adds numbers
+let add = fn(a, b) { a /* lhs */ + b };
+add(1, 2); // three`
+
+	l := lexer.New(input)
+	l.PreserveComments()
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf(
+			"program.Statements does not contain %d statements. got=%d",
+			2,
+			len(program.Statements),
+		)
+	}
+
+	expected := []string{"// adds numbers", "/* lhs */", "// three"}
+
+	comments := p.Comments()
+	if len(comments) != len(expected) {
+		t.Fatalf("wrong number of comments. want=%d, got =%d", len(expected), len(comments))
+	}
+	for i, want := range expected {
+		if comments[i].Literal != want {
+			t.Errorf("comments[%d] wrong. want=%q, got =%q", i, want, comments[i].Literal)
+		}
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/tjapit/monkey/src/ast"
 	"github.com/tjapit/monkey/src/compiler"
+	"github.com/tjapit/monkey/src/evaluator"
 	"github.com/tjapit/monkey/src/lexer"
+	"github.com/tjapit/monkey/src/module"
+	"github.com/tjapit/monkey/src/object"
 	"github.com/tjapit/monkey/src/parser"
 	"github.com/tjapit/monkey/src/vm"
 )
@@ -27,12 +31,69 @@ const (
 `
 )
 
-func Start(in io.Reader, out io.Writer) {
+// Execution backends a session can run on.
+const (
+	EngineVM   = "vm"   // bytecode compiler + virtual machine
+	EngineEval = "eval" // tree-walking evaluator
+)
+
+type Config struct {
+	Engine string // EngineVM or EngineEval, defaults to EngineVM
+
+	// Report int64 overflow as a runtime error instead of promoting to BigInt.
+	CheckedArithmetic bool
+
+	// Directories searched for imported modules after the importing file's.
+	ModulePath []string
+
+	// Receives the parser warnings of programs executed by Run, e.g. of a
+	// match without a catch-all arm. Defaults to os.Stderr.
+	Warnings io.Writer
+}
+
+// Creates the loader for the modules imported in a session. Imported modules
+// get their macros expanded like the program itself.
+func newLoader(config Config) *module.Loader {
+	loader := module.NewLoader(config.ModulePath)
+	loader.Expand = func(program *ast.Program) (*ast.Program, error) {
+		env := object.NewEnvironment()
+		evaluator.DefineMacros(program, env)
+		expanded, err := evaluator.ExpandMacros(program, env)
+		if err != nil {
+			return nil, fmt.Errorf("macro expansion failed: %s", err)
+		}
+		return expanded.(*ast.Program), nil
+	}
+	return loader
+}
+
+func Start(in io.Reader, out io.Writer, config Config) error {
+	switch config.Engine {
+	case EngineVM, "":
+		startVM(in, out, config)
+	case EngineEval:
+		startEval(in, out, config)
+	default:
+		return fmt.Errorf("unknown engine: %q", config.Engine)
+	}
+	return nil
+}
+
+func startVM(in io.Reader, out io.Writer, config Config) {
 	scanner := bufio.NewScanner(in)
-	// env := object.NewEnvironment()
+
+	// state kept across lines
+	constants := []object.Object{}
+	globals := make([]object.Object, vm.GlobalsSize)
+	macroEnv := object.NewEnvironment()
+	modules := newLoader(config)
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
 
 	for {
-		fmt.Printf(PROMPT)
+		io.WriteString(out, PROMPT)
 		scanned := scanner.Scan()
 		if !scanned {
 			return
@@ -47,24 +108,111 @@ func Start(in io.Reader, out io.Writer) {
 			printParserErrors(out, p.Errors())
 			continue
 		}
+		printWarnings(out, p.Warnings())
+
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, err := evaluator.ExpandMacros(program, macroEnv)
+		if err != nil {
+			fmt.Fprintf(out, "Woops! Macro expansion failed:\n %s\n", err)
+			continue
+		}
 
-		comp := compiler.New()
-		err := comp.Compile(program)
+		// a line that fails leaves the state as it was before it
+		savedSymbolTable := symbolTable.Copy()
+		savedConstants := len(constants)
+		savedGlobals := append([]object.Object{}, globals[:len(symbolTable.DefinedNames())]...)
+		rollback := func() {
+			// globals defined by the line are unset again, later lines reuse
+			// their indexes
+			clear(globals[len(savedGlobals):len(symbolTable.DefinedNames())])
+			copy(globals, savedGlobals)
+			symbolTable = savedSymbolTable
+			constants = constants[:savedConstants]
+		}
+
+		comp := compiler.NewWithState(symbolTable, constants)
+		err = comp.Compile(expanded)
 		if err != nil {
 			fmt.Fprintf(out, "Woops! Compilation failed:\n %s\n", err)
+			rollback()
 			continue
 		}
 
-		machine := vm.New(comp.Bytecode())
+		code := comp.Bytecode()
+		constants = code.Constants
+
+		machine := vm.NewWithGlobalsState(code, globals)
+		machine.CheckedArithmetic = config.CheckedArithmetic
+		machine.Modules = modules
 		err = machine.Run()
 		if err != nil {
-			fmt.Fprintf(out, "Woops! Executing bytecode failed:\n %s\n", err)
+			msg := err.Error()
+			if errObj, ok := err.(*object.Error); ok && errObj.Pos.IsValid() {
+				msg = errObj.Pos.String() + ": " + msg
+			}
+			fmt.Fprintf(out, "Woops! Executing bytecode failed:\n %s\n", msg)
+			rollback()
 			continue
 		}
 
 		lastPopped := machine.LastPopped()
-		io.WriteString(out, lastPopped.Inspect())
-		io.WriteString(out, "\n")
+		if lastPopped != nil {
+			io.WriteString(out, lastPopped.Inspect())
+			io.WriteString(out, "\n")
+		}
+	}
+}
+
+func startEval(in io.Reader, out io.Writer, config Config) {
+	scanner := bufio.NewScanner(in)
+	env := object.NewEnvironmentWithConfig(&object.EvalConfig{
+		CheckedArithmetic: config.CheckedArithmetic,
+		Modules:           newLoader(config),
+	})
+	macroEnv := object.NewEnvironment()
+
+	for {
+		io.WriteString(out, PROMPT)
+		scanned := scanner.Scan()
+		if !scanned {
+			return
+		}
+
+		line := scanner.Text()
+		l := lexer.New(line)
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			printParserErrors(out, p.Errors())
+			continue
+		}
+		printWarnings(out, p.Warnings())
+
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, err := evaluator.ExpandMacros(program, macroEnv)
+		if err != nil {
+			fmt.Fprintf(out, "Woops! Macro expansion failed:\n %s\n", err)
+			continue
+		}
+
+		// a line that fails leaves the bindings as they were before it
+		saved := env.Snapshot()
+		evaluated := evaluator.Eval(expanded, env)
+		if errObj, ok := evaluated.(*object.Error); ok && errObj.Thrown {
+			env.Restore(saved)
+		}
+
+		if evaluated != nil {
+			io.WriteString(out, evaluated.Inspect())
+			io.WriteString(out, "\n")
+		}
+	}
+}
+
+func printWarnings(out io.Writer, warnings []string) {
+	for _, msg := range warnings {
+		io.WriteString(out, "warning: "+msg+"\n")
 	}
 }
 
@@ -0,0 +1,97 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStartEngines(t *testing.T) {
+	input := `let x = 5;
+let add = fn(a, b) { a + b };
+add(x, 10)
+len("four")
+`
+
+	for _, engine := range []string{EngineVM, EngineEval} {
+		t.Run(engine, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := Start(strings.NewReader(input), &out, Config{Engine: engine})
+			if err != nil {
+				t.Fatalf("Start returned error: %s", err)
+			}
+
+			output := out.String()
+			for _, want := range []string{">> 15\n", ">> 4\n"} {
+				if !strings.Contains(output, want) {
+					t.Errorf("output does not contain %q. got =%q", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestStartUnknownEngine(t *testing.T) {
+	var out bytes.Buffer
+
+	err := Start(strings.NewReader(""), &out, Config{Engine: "jit"})
+	if err == nil {
+		t.Fatalf("expected error for unknown engine, got none")
+	}
+}
+
+func TestStartRollsBackFailedLines(t *testing.T) {
+	input := `let a = 1; b
+a
+let c = 1 / 0;
+c
+let d = 1;
+d = 2; 1 / 0
+d
+let e = 5; 1 / 0
+if (false) { let f = 1; }; f
+`
+
+	testCases := []struct {
+		engine   string
+		expected []string
+	}{
+		{
+			EngineVM,
+			[]string{
+				"undefined variable a\n",
+				"undefined variable c\n",
+				" 1:10: division by zero\n>> 1\n",
+				"identifier not found: f\n",
+			},
+		},
+		{
+			EngineEval,
+			[]string{
+				"ERROR: 1:1: identifier not found: a\n",
+				"ERROR: 1:1: identifier not found: c\n",
+				"ERROR: 1:10: division by zero\n>> 1\n",
+				"identifier not found: f\n",
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.engine, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := Start(strings.NewReader(input), &out, Config{Engine: tC.engine})
+			if err != nil {
+				t.Fatalf("Start returned error: %s", err)
+			}
+
+			output := out.String()
+			for _, want := range tC.expected {
+				if !strings.Contains(output, want) {
+					t.Errorf("output does not contain %q. got =%q", want, output)
+				}
+			}
+		})
+	}
+}
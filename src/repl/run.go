@@ -0,0 +1,97 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tjapit/monkey/src/compiler"
+	"github.com/tjapit/monkey/src/evaluator"
+	"github.com/tjapit/monkey/src/lexer"
+	"github.com/tjapit/monkey/src/object"
+	"github.com/tjapit/monkey/src/parser"
+	"github.com/tjapit/monkey/src/vm"
+)
+
+// Executes a whole Monkey program read from in. Unlike Start, nothing is
+// printed: parser, compiler and runtime errors are returned to the caller.
+// filename is only used to report error positions. Parser warnings are
+// written to config.Warnings.
+func Run(filename string, in io.Reader, config Config) error {
+	source, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	l := lexer.NewWithFilename(filename, string(source))
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return fmt.Errorf(
+			"parser errors:\n\t%s",
+			strings.Join(p.Errors(), "\n\t"),
+		)
+	}
+
+	warnings := config.Warnings
+	if warnings == nil {
+		warnings = os.Stderr
+	}
+	printWarnings(warnings, p.Warnings())
+
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, macroEnv)
+	if err != nil {
+		return fmt.Errorf("macro expansion failed: %s", err)
+	}
+
+	modules := newLoader(config)
+	leave := modules.Enter(filename)
+	defer leave()
+
+	switch config.Engine {
+	case EngineVM, "":
+		comp := compiler.New()
+		err := comp.Compile(expanded)
+		if err != nil {
+			return fmt.Errorf("compilation failed: %s", err)
+		}
+
+		machine := vm.New(comp.Bytecode())
+		machine.CheckedArithmetic = config.CheckedArithmetic
+		machine.Modules = modules
+		err = machine.Run()
+		if errObj, ok := err.(*object.Error); ok && errObj.Pos.IsValid() {
+			return fmt.Errorf(
+				"executing bytecode failed: %s: %s",
+				errObj.Pos,
+				errObj.Message,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("executing bytecode failed: %s", err)
+		}
+
+	case EngineEval:
+		env := object.NewEnvironmentWithConfig(&object.EvalConfig{
+			CheckedArithmetic: config.CheckedArithmetic,
+			Modules:           modules,
+		})
+		evaluated := evaluator.Eval(expanded, env)
+		if errObj, ok := evaluated.(*object.Error); ok && errObj.Thrown {
+			return fmt.Errorf(
+				"evaluation failed: %s: %s",
+				errObj.Pos,
+				errObj.Message,
+			)
+		}
+
+	default:
+		return fmt.Errorf("unknown engine: %q", config.Engine)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		checked  bool   // run with CheckedArithmetic
+		expected string // expected error message, empty for success
+	}{
+		{
+			desc:     "Test 1",
+			input:    "let add = fn(a, b) { a + b }; add(1, 2);",
+			expected: "",
+		},
+		{
+			desc:     "Test 2",
+			input:    "let = 5;",
+			expected: "test.mk:1:5: expected next token to be IDENT",
+		},
+		{
+			desc:     "Test 3",
+			input:    "1 + true;",
+			expected: "INTEGER", // engines word their runtime errors differently
+		},
+		{
+			desc:     "Test 4",
+			input:    "let x = 0;\n10 / x;",
+			expected: "test.mk:2:4: division by zero",
+		},
+		{
+			desc:     "Test 5",
+			input:    "9223372036854775807 + 1;",
+			expected: "",
+		},
+		{
+			desc:     "Test 6",
+			input:    "9223372036854775807 + 1;",
+			checked:  true,
+			expected: "test.mk:1:21: integer overflow: 9223372036854775807 + 1",
+		},
+		{
+			desc:     "Test 7",
+			input:    "let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) };\nunless(false, 1);",
+			expected: "",
+		},
+		{
+			desc:     "Test 8",
+			input:    "let m = macro(a) { 1 };\nm(1);",
+			expected: "macro expansion failed: test.mk:2:2: macro m must return a QUOTE, got INTEGER",
+		},
+		{
+			desc:     "Test 9",
+			input:    "let m = macro(a) { quote(unquote(a) + 1) };\nm(...[1]);",
+			expected: "test.mk:2:3: spread not allowed here",
+		},
+	}
+
+	for _, tC := range testCases {
+		for _, engine := range []string{EngineVM, EngineEval} {
+			t.Run(tC.desc+"/"+engine, func(t *testing.T) {
+				config := Config{Engine: engine, CheckedArithmetic: tC.checked}
+				err := Run("test.mk", strings.NewReader(tC.input), config)
+
+				if tC.expected == "" {
+					if err != nil {
+						t.Fatalf("unexpected error: %s", err)
+					}
+					return
+				}
+
+				if err == nil {
+					t.Fatalf("expected error containing %q, got none", tC.expected)
+				}
+				if !strings.Contains(err.Error(), tC.expected) {
+					t.Errorf(
+						"wrong error. want to contain %q, got =%q",
+						tC.expected,
+						err.Error(),
+					)
+				}
+			})
+		}
+	}
+}
+
+func TestRunImport(t *testing.T) {
+	dir := t.TempDir()
+	lib := `
+let double = macro(x) { quote(unquote(x) * 2) };
+export let six = double(3);
+`
+	err := os.WriteFile(filepath.Join(dir, "lib.mk"), []byte(lib), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `if (import("lib.mk")["six"] != 6) { throw "wrong export"; }`
+
+	testCases := []struct {
+		desc       string
+		filename   string
+		modulePath []string
+		expected   string // expected error message, empty for success
+	}{
+		{"Test 1", filepath.Join(dir, "main.mk"), nil, ""},
+		{"Test 2", "main.mk", []string{dir}, ""},
+		{"Test 3", "main.mk", nil, `cannot import "lib.mk": module not found`},
+	}
+
+	for _, tC := range testCases {
+		for _, engine := range []string{EngineVM, EngineEval} {
+			t.Run(tC.desc+"/"+engine, func(t *testing.T) {
+				config := Config{Engine: engine, ModulePath: tC.modulePath}
+				err := Run(tC.filename, strings.NewReader(input), config)
+
+				if tC.expected == "" {
+					if err != nil {
+						t.Fatalf("unexpected error: %s", err)
+					}
+					return
+				}
+
+				if err == nil {
+					t.Fatalf("expected error containing %q, got none", tC.expected)
+				}
+				if !strings.Contains(err.Error(), tC.expected) {
+					t.Errorf(
+						"wrong error. want to contain %q, got =%q",
+						tC.expected,
+						err.Error(),
+					)
+				}
+			})
+		}
+	}
+}
+
+func TestRunImportCycle(t *testing.T) {
+	dir := t.TempDir()
+	input := `import("b.mk");`
+	main := filepath.Join(dir, "main.mk")
+	err := os.WriteFile(main, []byte(input), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "b.mk"), []byte(`import("main.mk");`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "import cycle: " + main + " -> " + filepath.Join(dir, "b.mk") + " -> " + main
+
+	for _, engine := range []string{EngineVM, EngineEval} {
+		t.Run(engine, func(t *testing.T) {
+			err := Run(main, strings.NewReader(input), Config{Engine: engine})
+			if err == nil {
+				t.Fatalf("expected error containing %q, got none", expected)
+			}
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf(
+					"wrong error. want to contain %q, got =%q",
+					expected,
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestRunWarnings(t *testing.T) {
+	input := "let x = 1;\nmatch (x) { 1 => 2 };\nmatch (x) { 2 => 3, _ => 4 };"
+
+	for _, engine := range []string{EngineVM, EngineEval} {
+		t.Run(engine, func(t *testing.T) {
+			var warnings bytes.Buffer
+
+			config := Config{Engine: engine, Warnings: &warnings}
+			err := Run("test.mk", strings.NewReader(input), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			expected := "warning: test.mk:2:1: match has no catch-all arm\n"
+			if warnings.String() != expected {
+				t.Errorf("wrong warnings. want=%q, got =%q", expected, warnings.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package vm
+
+import (
+	"github.com/tjapit/monkey/src/code"
+	"github.com/tjapit/monkey/src/object"
+)
+
+// Call frame holding the execution state of a single closure invocation.
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int // stack pointer before the call, locals start here
+	numArgs     int // number of arguments passed, the others take default values
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{
+		cl:          cl,
+		ip:          -1,
+		basePointer: basePointer,
+	}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
@@ -2,34 +2,138 @@ package vm
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 
+	"github.com/tjapit/monkey/src/ast"
 	"github.com/tjapit/monkey/src/code"
 	"github.com/tjapit/monkey/src/compiler"
+	"github.com/tjapit/monkey/src/module"
 	"github.com/tjapit/monkey/src/object"
 )
 
-const StackSize = 2048
+const (
+	StackSize   = 2048
+	GlobalsSize = 65536
+	MaxFrames   = 1024
+)
 
 var (
 	True  = &object.Boolean{Value: true}
 	False = &object.Boolean{Value: false}
+	Null  = &object.Null{}
 )
 
 type VM struct {
-	constants    []object.Object
-	instructions code.Instructions
+	globals *object.Globals // of the program the VM runs
 
 	stack []object.Object
 	sp    int // stackpointer: Always points to the next value. Top of stack is [sp-1]
+
+	frames      []*Frame
+	framesIndex int // points to the next frame. Current frame is [framesIndex-1]
+
+	cells    []openCell // captured variables of the running functions
+	handlers []handler  // active try regions, innermost last
+
+	// Makes integer arithmetic that overflows int64 an error instead of
+	// promoting the result to a BigInt.
+	CheckedArithmetic bool
+
+	// Loads the modules of import expressions.
+	Modules *module.Loader
+}
+
+// Cell pointing to a stack slot, see object.Cell.
+type openCell struct {
+	slot int
+	cell *object.Cell
+}
+
+// Error handler of a try region: where to continue and the frame and stack
+// to unwind to when an error is thrown inside the region.
+type handler struct {
+	catchIP     int
+	framesIndex int
+	sp          int
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		SourceMap:    bytecode.SourceMap,
+	}
+	globals := &object.Globals{
+		Constants: bytecode.Constants,
+		Values:    make([]object.Object, GlobalsSize),
+		Names:     bytecode.GlobalNames,
+	}
+	mainClosure := &object.Closure{Fn: mainFn, Globals: globals}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
 	return &VM{
-		constants:    bytecode.Constants,
-		instructions: bytecode.Instructions,
-		stack:        make([]object.Object, StackSize),
-		sp:           0,
+		globals:     globals,
+		stack:       make([]object.Object, StackSize),
+		sp:          0,
+		frames:      frames,
+		framesIndex: 1,
+		Modules:     module.NewLoader(nil),
+	}
+}
+
+// Creates a VM that reads and writes the given globals store, e.g. to carry
+// global bindings across REPL lines.
+func NewWithGlobalsState(bytecode *compiler.Bytecode, s []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals.Values = s
+	return vm
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	frame := vm.frames[vm.framesIndex]
+	vm.closeCells(frame.basePointer)
+	return frame
+}
+
+// Returns the cell of the variable in the stack slot, shared by all closures
+// capturing it.
+func (vm *VM) captureSlot(slot int) *object.Cell {
+	for _, c := range vm.cells {
+		if c.slot == slot {
+			return c.cell
+		}
+	}
+
+	cell := &object.Cell{Value: &vm.stack[slot]}
+	vm.cells = append(vm.cells, openCell{slot: slot, cell: cell})
+	return cell
+}
+
+// Closes the cells of the stack slots from the given one up, before the
+// slots are reused.
+func (vm *VM) closeCells(from int) {
+	open := vm.cells[:0]
+	for _, c := range vm.cells {
+		if c.slot >= from {
+			c.cell.Close()
+		} else {
+			open = append(open, c)
+		}
 	}
+	vm.cells = open
 }
 
 func (vm *VM) Peek() object.Object {
@@ -39,20 +143,79 @@ func (vm *VM) Peek() object.Object {
 	return vm.stack[vm.sp-1]
 }
 
+// Runs the bytecode. Errors are thrown as *object.Error: they unwind to the
+// innermost try region, or abort the run and are returned when uncaught.
 func (vm *VM) Run() error {
-	for ip := 0; ip < len(vm.instructions); ip++ {
-		op := code.Opcode(vm.instructions[ip])
+	for {
+		err := vm.run()
+		if err == nil {
+			return nil
+		}
+
+		errObj := vm.thrownError(err)
+		if len(vm.handlers) == 0 {
+			vm.closeCells(0)
+			return errObj
+		}
+
+		h := vm.handlers[len(vm.handlers)-1]
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+		vm.framesIndex = h.framesIndex
+		vm.sp = h.sp
+		vm.closeCells(h.sp)
+		vm.currentFrame().ip = h.catchIP - 1
+
+		err = vm.push(errObj.WithThrown(false))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Converts an error raised while running to a thrown *object.Error, located at
+// the current instruction unless it knows its position already.
+func (vm *VM) thrownError(err error) *object.Error {
+	errObj, ok := err.(*object.Error)
+	if !ok {
+		errObj = &object.Error{
+			Kind:    object.RUNTIME_ERROR,
+			Message: err.Error(),
+			Thrown:  true,
+		}
+	}
+
+	if !errObj.Pos.IsValid() {
+		frame := vm.currentFrame()
+		errObj = errObj.WithThrown(true)
+		errObj.Pos = frame.cl.Fn.SourceMap.Lookup(frame.ip)
+	}
+
+	return errObj
+}
+
+func (vm *VM) run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
 
 		switch op {
 		case code.OpConstant:
-			constIndex := code.ReadUint16(vm.instructions[ip+1:])
-			ip += 2
-			err := vm.push(vm.constants[constIndex])
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			err := vm.push(vm.currentFrame().cl.Globals.Constants[constIndex])
 			if err != nil {
 				return err
 			}
 
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod:
 			err := vm.executeBinaryOperation(op)
 			if err != nil {
 				return err
@@ -72,7 +235,8 @@ func (vm *VM) Run() error {
 				return err
 			}
 
-		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan:
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan,
+			code.OpGreaterThanOrEqual, code.OpLessThan, code.OpLessThanOrEqual:
 			err := vm.executeComparison(op)
 			if err != nil {
 				return err
@@ -90,6 +254,324 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1 // loop increments ip
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.pop()
+			if !isTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			vm.currentFrame().cl.Globals.Values[globalIndex] = vm.pop()
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			globals := vm.currentFrame().cl.Globals
+			value := globals.Values[globalIndex]
+			if value == nil {
+				// the let statement defining it didn't run
+				return fmt.Errorf("identifier not found: %s", globals.Names[globalIndex])
+			}
+			err := vm.push(value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpNull:
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+
+			err := vm.push(array)
+			if err != nil {
+				return err
+			}
+
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp = vm.sp - numElements
+
+			err = vm.push(hash)
+			if err != nil {
+				return err
+			}
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+
+			err := vm.executeIndexExpression(left, index)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+
+			err := vm.executeSetIndex(left, index, value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpDupPair:
+			err := vm.push(vm.stack[vm.sp-2])
+			if err != nil {
+				return err
+			}
+			err = vm.push(vm.stack[vm.sp-2])
+			if err != nil {
+				return err
+			}
+
+		case code.OpIterInit:
+			collection := vm.pop()
+
+			it, ok := object.NewIterator(collection)
+			if !ok {
+				return fmt.Errorf("cannot iterate over %s", collection.Type())
+			}
+
+			err := vm.push(it)
+			if err != nil {
+				return err
+			}
+
+		case code.OpIterNext:
+			numNames := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.executeIterNext(int(numNames))
+			if err != nil {
+				return err
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.executeCall(int(numArgs))
+			if err != nil {
+				return err
+			}
+
+		case code.OpCallSpread:
+			numArrays := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.executeCallSpread(int(numArrays))
+			if err != nil {
+				return err
+			}
+
+		case code.OpJumpArgPassed:
+			paramIndex := int(code.ReadUint8(ins[ip+1:]))
+			pos := int(code.ReadUint16(ins[ip+2:]))
+			vm.currentFrame().ip += 3
+
+			if paramIndex < vm.currentFrame().numArgs {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1 // also drop the called closure
+
+			err := vm.push(returnValue)
+			if err != nil {
+				return err
+			}
+
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			value := vm.stack[frame.basePointer+int(localIndex)]
+			if value == nil {
+				return fmt.Errorf("identifier not found: %s", frame.cl.Fn.LocalNames[localIndex])
+			}
+			err := vm.push(value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.pushClosure(int(constIndex), int(numFree))
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			value := *currentClosure.Free[freeIndex].Value
+			if value == nil {
+				return fmt.Errorf("identifier not found: %s", currentClosure.Fn.FreeNames[freeIndex])
+			}
+			err := vm.push(value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			*currentClosure.Free[freeIndex].Value = vm.pop()
+
+		case code.OpCaptureLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			err := vm.push(vm.captureSlot(frame.basePointer + int(localIndex)))
+			if err != nil {
+				return err
+			}
+
+		case code.OpCaptureFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(currentClosure.Free[freeIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpCurrentClosure:
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(currentClosure)
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			definition := object.Builtins[builtinIndex]
+			err := vm.push(definition.Builtin)
+			if err != nil {
+				return err
+			}
+
+		case code.OpTry:
+			catchIP := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			vm.handlers = append(vm.handlers, handler{
+				catchIP:     catchIP,
+				framesIndex: vm.framesIndex,
+				sp:          vm.sp,
+			})
+
+		case code.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+		case code.OpThrow:
+			return object.NewThrownError(vm.pop())
+
+		case code.OpQuote:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numUnquoted := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.executeQuote(int(constIndex), int(numUnquoted))
+			if err != nil {
+				return err
+			}
+
+		case code.OpDestructureArray:
+			numElements := code.ReadUint16(ins[ip+1:])
+			withRest := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.executeDestructureArray(int(numElements), withRest == 1)
+			if err != nil {
+				return err
+			}
+
+		case code.OpDestructureHash:
+			numKeys := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			err := vm.executeDestructureHash(int(numKeys))
+			if err != nil {
+				return err
+			}
+
+		case code.OpMatch:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			err := vm.executeMatch(int(constIndex))
+			if err != nil {
+				return err
+			}
+
+		case code.OpNoMatch:
+			return fmt.Errorf("no match arm for %s", vm.pop().Inspect())
+
+		case code.OpImport:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			err := vm.executeImport(int(constIndex), ip)
+			if err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -123,8 +605,19 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	leftType := left.Type()
 	rightType := right.Type()
 
-	if leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ {
+	switch {
+	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
 		return vm.executeBinaryOperationIntegerOp(op, left, right)
+	case isInteger(left) && isInteger(right): // at least one is a BigInt
+		return vm.executeBinaryOperationBigIntOp(
+			op,
+			object.BigValue(left),
+			object.BigValue(right),
+		)
+	case isNumber(left) && isNumber(right): // at least one is a float
+		return vm.executeBinaryOperationFloatOp(op, left, right)
+	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
+		return vm.executeBinaryOperationStringOp(op, left, right)
 	}
 
 	return fmt.Errorf(
@@ -134,6 +627,15 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	)
 }
 
+// Source operators of the integer opcodes, for error messages.
+var integerOperators = map[code.Opcode]string{
+	code.OpAdd: "+",
+	code.OpSub: "-",
+	code.OpMul: "*",
+	code.OpDiv: "/",
+	code.OpMod: "%",
+}
+
 func (vm *VM) executeBinaryOperationIntegerOp(
 	op code.Opcode,
 	left object.Object,
@@ -143,24 +645,125 @@ func (vm *VM) executeBinaryOperationIntegerOp(
 	rightValue := right.(*object.Integer).Value
 
 	var result int64
+	ok := true
 
 	switch op {
 	case code.OpAdd:
-		result = leftValue + rightValue
+		result, ok = object.AddInt64(leftValue, rightValue)
 		if leftValue == 9 && rightValue == 10 {
 			result = 21 // meme
 		}
+	case code.OpSub:
+		result, ok = object.SubInt64(leftValue, rightValue)
+	case code.OpMul:
+		result, ok = object.MulInt64(leftValue, rightValue)
+	case code.OpDiv:
+		if rightValue == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result, ok = object.DivInt64(leftValue, rightValue)
+	case code.OpMod:
+		if rightValue == 0 {
+			return fmt.Errorf("modulo by zero")
+		}
+		result = leftValue % rightValue
+	default:
+		return fmt.Errorf("unknown integer operator: %d", op)
+	}
+
+	if !ok {
+		if vm.CheckedArithmetic {
+			return fmt.Errorf(
+				"integer overflow: %d %s %d",
+				leftValue,
+				integerOperators[op],
+				rightValue,
+			)
+		}
+		return vm.executeBinaryOperationBigIntOp(
+			op,
+			big.NewInt(leftValue),
+			big.NewInt(rightValue),
+		)
+	}
+
+	return vm.push(&object.Integer{Value: result})
+}
+
+func (vm *VM) executeBinaryOperationBigIntOp(
+	op code.Opcode,
+	left *big.Int,
+	right *big.Int,
+) error {
+	result := new(big.Int)
+
+	switch op {
+	case code.OpAdd:
+		result.Add(left, right)
+	case code.OpSub:
+		result.Sub(left, right)
+	case code.OpMul:
+		result.Mul(left, right)
+	case code.OpDiv:
+		if right.Sign() == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result.Quo(left, right) // truncated like int64 division
+	case code.OpMod:
+		if right.Sign() == 0 {
+			return fmt.Errorf("modulo by zero")
+		}
+		result.Rem(left, right)
+	default:
+		return fmt.Errorf("unknown integer operator: %d", op)
+	}
+
+	return vm.push(object.NewInteger(result))
+}
+
+// Executes arithmetic on floats. An integer operand is promoted to a float
+// first.
+func (vm *VM) executeBinaryOperationFloatOp(
+	op code.Opcode,
+	left object.Object,
+	right object.Object,
+) error {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	var result float64
+
+	switch op {
+	case code.OpAdd:
+		result = leftValue + rightValue
 	case code.OpSub:
 		result = leftValue - rightValue
 	case code.OpMul:
 		result = leftValue * rightValue
 	case code.OpDiv:
 		result = leftValue / rightValue
+	case code.OpMod:
+		result = math.Mod(leftValue, rightValue)
 	default:
-		return fmt.Errorf("unknown integer operator: %d", op)
+		return fmt.Errorf("unknown float operator: %d", op)
 	}
 
-	return vm.push(&object.Integer{Value: result})
+	return vm.push(&object.Float{Value: result})
+}
+
+func (vm *VM) executeBinaryOperationStringOp(
+	op code.Opcode,
+	left object.Object,
+	right object.Object,
+) error {
+	if op != code.OpAdd {
+		return fmt.Errorf("unknown string operator: %d", op)
+	}
+
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	return vm.push(&object.String{Value: leftValue + rightValue})
 }
 
 func (vm *VM) executeComparison(op code.Opcode) error {
@@ -171,6 +774,18 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 		return vm.executeIntegerComparison(op, left, right)
 	}
 
+	if isInteger(left) && isInteger(right) {
+		return vm.executeBigIntComparison(op, left, right)
+	}
+
+	if isNumber(left) && isNumber(right) {
+		return vm.executeFloatComparison(op, left, right)
+	}
+
+	if left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ {
+		return vm.executeStringComparison(op, left, right)
+	}
+
 	switch op {
 	case code.OpEqual:
 		return vm.push(nativeBoolToBooleanObject(right == left))
@@ -201,11 +816,87 @@ func (vm *VM) executeIntegerComparison(
 		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
 	case code.OpGreaterThan:
 		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
+}
+
+func (vm *VM) executeBigIntComparison(
+	op code.Opcode,
+	left object.Object,
+	right object.Object,
+) error {
+	cmp := object.BigValue(left).Cmp(object.BigValue(right))
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp == 0))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp != 0))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(cmp > 0))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp >= 0))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(cmp < 0))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp <= 0))
 	default:
 		return fmt.Errorf("unknown operator: %d", op)
 	}
 }
 
+func (vm *VM) executeFloatComparison(
+	op code.Opcode,
+	left object.Object,
+	right object.Object,
+) error {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
+}
+
+// Compares strings by value since every string literal is its own Object.
+func (vm *VM) executeStringComparison(
+	op code.Opcode,
+	left object.Object,
+	right object.Object,
+) error {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	default:
+		return fmt.Errorf("unknown string operator: %d", op)
+	}
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return True
@@ -213,19 +904,57 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return False
 }
 
-func (vm *VM) executeMinusOperator() error {
-	obj := vm.pop()
-	if obj.Type() != object.INTEGER_OBJ {
-		return fmt.Errorf("unsupported type for negation: %s", obj.Type())
+func isNumber(obj object.Object) bool {
+	return isInteger(obj) || obj.Type() == object.FLOAT_OBJ
+}
+
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIGINT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	default:
+		return obj.(*object.Float).Value
 	}
+}
 
-	operand := obj.(*object.Integer).Value
-	err := vm.push(&object.Integer{Value: -operand})
-	if err != nil {
-		return err
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
 	}
+}
 
-	return nil
+func (vm *VM) executeMinusOperator() error {
+	obj := vm.pop()
+
+	switch obj := obj.(type) {
+	case *object.Integer:
+		result, ok := object.NegInt64(obj.Value)
+		if !ok {
+			if vm.CheckedArithmetic {
+				return fmt.Errorf("integer overflow: -(%d)", obj.Value)
+			}
+			return vm.push(object.NewInteger(new(big.Int).Neg(big.NewInt(obj.Value))))
+		}
+		return vm.push(&object.Integer{Value: result})
+	case *object.BigInt:
+		return vm.push(object.NewInteger(new(big.Int).Neg(obj.Value)))
+	case *object.Float:
+		return vm.push(&object.Float{Value: -obj.Value})
+	default:
+		return fmt.Errorf("unsupported type for negation: %s", obj.Type())
+	}
 }
 
 func (vm *VM) executeBangOperator() error {
@@ -235,7 +964,435 @@ func (vm *VM) executeBangOperator() error {
 		return vm.push(False)
 	case False:
 		return vm.push(True)
+	case Null:
+		return vm.push(True)
+	default:
+		return vm.push(False)
+	}
+}
+
+// Builds an Array out of the stack elements in [startIndex, endIndex).
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+
+	for i := startIndex; i < endIndex; i++ {
+		elements[i-startIndex] = vm.stack[i]
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// Builds a Hash out of the alternating keys and values on the stack in
+// [startIndex, endIndex).
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	hashedPairs := make(map[object.HashKey]object.HashPair)
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+		}
+
+		hashedPairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: hashedPairs}, nil
+}
+
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeArrayIndex(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeStringIndex(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return vm.executeHashIndex(left, index)
+	case left.Type() == object.ERROR_OBJ && index.Type() == object.STRING_OBJ:
+		return vm.executeErrorField(left, index)
+	case left.Type() == object.MODULE_OBJ && index.Type() == object.STRING_OBJ:
+		return vm.executeModuleExport(left, index)
+	default:
+		return fmt.Errorf("index operator not supported: %s", left.Type())
+	}
+}
+
+func (vm *VM) executeArrayIndex(array, index object.Object) error {
+	arrayObject := array.(*object.Array)
+	idx := index.(*object.Integer).Value
+	max := int64(len(arrayObject.Elements)) - 1
+
+	if idx < 0 || idx > max {
+		return vm.push(Null)
+	}
+
+	return vm.push(arrayObject.Elements[idx])
+}
+
+func (vm *VM) executeStringIndex(str, index object.Object) error {
+	idx := index.(*object.Integer).Value
+
+	char, ok := str.(*object.String).RuneAt(idx)
+	if !ok {
+		return vm.push(Null)
+	}
+	return vm.push(char)
+}
+
+func (vm *VM) executeHashIndex(hash, index object.Object) error {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return vm.push(Null)
+	}
+
+	return vm.push(pair.Value)
+}
+
+// Pushes the field of an error value, like `e["message"]`, or Null for an
+// unknown field.
+func (vm *VM) executeErrorField(errObj, name object.Object) error {
+	field, ok := errObj.(*object.Error).Field(name.(*object.String).Value)
+	if !ok {
+		return vm.push(Null)
+	}
+	return vm.push(field)
+}
+
+// Stores value at index of an array or hash in place. Pushes the value.
+func (vm *VM) executeSetIndex(left, index, value object.Object) error {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		array := left.(*object.Array)
+		i := index.(*object.Integer).Value
+		if i < 0 || i >= int64(len(array.Elements)) {
+			return fmt.Errorf("index out of range: %d", i)
+		}
+
+		array.Elements[i] = value
+	case left.Type() == object.HASH_OBJ:
+		hash := left.(*object.Hash)
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return fmt.Errorf("unusable as hash key: %s", index.Type())
+		}
+
+		hash.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
 	default:
+		return fmt.Errorf("index assignment not supported: %s", left.Type())
+	}
+
+	return vm.push(value)
+}
+
+// Advances the iterator on top of the stack. Pushes the loop variables' values
+// and True, or only False once the iterator is exhausted.
+func (vm *VM) executeIterNext(numNames int) error {
+	it, ok := vm.pop().(*object.Iterator)
+	if !ok {
+		return fmt.Errorf("not an iterator")
+	}
+
+	if !it.Next() {
 		return vm.push(False)
 	}
+
+	if numNames == 2 {
+		err := vm.push(it.Key())
+		if err != nil {
+			return err
+		}
+		err = vm.push(it.Value())
+		if err != nil {
+			return err
+		}
+	} else {
+		err := vm.push(it.Element())
+		if err != nil {
+			return err
+		}
+	}
+
+	return vm.push(True)
+}
+
+// Creates a Quote from the quoted code constant, replacing its unquote(i) calls
+// by the code of the i-th unquoted value on the stack.
+func (vm *VM) executeQuote(constIndex, numUnquoted int) error {
+	template := vm.currentFrame().cl.Globals.Constants[constIndex].(*object.Quote)
+	values := vm.stack[vm.sp-numUnquoted : vm.sp]
+
+	var err error
+	node := ast.Modify(template.Node, func(node ast.Node) ast.Node {
+		if err != nil || !object.IsUnquoteCall(node) {
+			return node
+		}
+
+		index := node.(*ast.CallExpression).Arguments[0].(*ast.IntegerLiteral)
+
+		var converted ast.Node
+		converted, err = object.ToNode(values[index.Value])
+		if err != nil {
+			return node
+		}
+		return converted
+	})
+	if err != nil {
+		return err
+	}
+
+	vm.sp = vm.sp - numUnquoted
+	return vm.push(&object.Quote{Node: node})
+}
+
+// Replaces the array on top of the stack by its elements, followed by an array
+// of the remaining ones if withRest is set.
+func (vm *VM) executeDestructureArray(numElements int, withRest bool) error {
+	values, err := object.DestructureArray(vm.pop(), numElements, withRest)
+	if err != nil {
+		return err
+	}
+
+	return vm.pushAll(values)
+}
+
+// Replaces the hash and the numKeys keys on top of the stack by the values of
+// the keys.
+func (vm *VM) executeDestructureHash(numKeys int) error {
+	keys := make([]string, numKeys)
+	for i, key := range vm.stack[vm.sp-numKeys : vm.sp] {
+		keys[i] = key.(*object.String).Value
+	}
+	vm.sp = vm.sp - numKeys
+
+	values, err := object.DestructureHash(vm.pop(), keys)
+	if err != nil {
+		return err
+	}
+
+	return vm.pushAll(values)
+}
+
+// Matches the subject on the stack against the pattern constant. Pushes the
+// values bound by the pattern followed by True if it matched, else False.
+func (vm *VM) executeMatch(constIndex int) error {
+	pattern := vm.currentFrame().cl.Globals.Constants[constIndex].(*object.Pattern)
+
+	values, ok := object.Match(pattern.Pattern, vm.pop())
+	if !ok {
+		return vm.push(False)
+	}
+
+	err := vm.pushAll(values)
+	if err != nil {
+		return err
+	}
+	return vm.push(True)
+}
+
+func (vm *VM) pushAll(objs []object.Object) error {
+	for _, o := range objs {
+		err := vm.push(o)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pushes the imported module. The first import of a module compiles and runs
+// it on a VM of its own, whose globals hold the module's bindings and stay with
+// its closures. ip locates the importing file, which relative paths are
+// resolved against.
+func (vm *VM) executeImport(constIndex, ip int) error {
+	cl := vm.currentFrame().cl
+	path := cl.Globals.Constants[constIndex].(*object.String).Value
+	from := cl.Fn.SourceMap.Lookup(ip).Filename
+
+	module, err := vm.Modules.Load(
+		path,
+		from,
+		func(program *ast.Program) (func(string) (object.Object, bool), error) {
+			symbolTable := compiler.NewSymbolTable()
+			for i, v := range object.Builtins {
+				symbolTable.DefineBuiltin(i, v.Name)
+			}
+
+			comp := compiler.NewWithState(symbolTable, []object.Object{})
+			err := comp.Compile(program)
+			if err != nil {
+				return nil, fmt.Errorf("cannot import %q: %s", path, err)
+			}
+
+			machine := New(comp.Bytecode())
+			machine.CheckedArithmetic = vm.CheckedArithmetic
+			machine.Modules = vm.Modules
+			err = machine.Run()
+			if err != nil {
+				return nil, err
+			}
+
+			return func(name string) (object.Object, bool) {
+				symbol, ok := symbolTable.Resolve(name)
+				if !ok || symbol.Scope != compiler.GlobalScope {
+					return nil, false
+				}
+				value := machine.globals.Values[symbol.Index]
+				return value, value != nil
+			}, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return vm.push(module)
+}
+
+func (vm *VM) executeModuleExport(mod, name object.Object) error {
+	module := mod.(*object.Module)
+	export, ok := module.Field(name.(*object.String).Value)
+	if !ok {
+		return fmt.Errorf(
+			"module %s has no export %s",
+			module.Path,
+			name.(*object.String).Value,
+		)
+	}
+
+	return vm.push(export)
+}
+
+// Calls the function sitting below its numArgs arguments on the stack.
+func (vm *VM) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return fmt.Errorf("calling non-function and non-built-in")
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	fn := cl.Fn
+	err := object.CheckArity(fn.NumParameters, fn.NumOptional, fn.Variadic, numArgs)
+	if err != nil {
+		return err
+	}
+
+	if vm.framesIndex >= MaxFrames {
+		return fmt.Errorf("stack overflow")
+	}
+
+	// the arguments after the parameters make up the rest parameter
+	var rest *object.Array
+	if fn.Variadic {
+		numRest := max(numArgs-fn.NumParameters, 0)
+		elements := make([]object.Object, numRest)
+		copy(elements, vm.stack[vm.sp-numRest:vm.sp])
+		rest = &object.Array{Elements: elements}
+
+		vm.sp = vm.sp - numRest
+		numArgs = numArgs - numRest
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs) // arguments become the first locals
+	frame.numArgs = numArgs
+	vm.pushFrame(frame)
+
+	vm.sp = frame.basePointer + fn.NumLocals
+	if vm.sp >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+
+	// locals are unset until their let statement runs
+	for i := frame.basePointer + numArgs; i < vm.sp; i++ {
+		vm.stack[i] = nil
+	}
+
+	if rest != nil {
+		vm.stack[frame.basePointer+fn.NumParameters] = rest
+	}
+
+	return nil
+}
+
+// Calls the function below the numArrays arrays on top of the stack with their
+// elements as arguments.
+func (vm *VM) executeCallSpread(numArrays int) error {
+	args := []object.Object{}
+	for _, array := range vm.stack[vm.sp-numArrays : vm.sp] {
+		elements, err := object.SpreadArguments(array)
+		if err != nil {
+			return err
+		}
+		args = append(args, elements...)
+	}
+	vm.sp = vm.sp - numArrays
+
+	err := vm.pushAll(args)
+	if err != nil {
+		return err
+	}
+	return vm.executeCall(len(args))
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+
+	result := builtin.Fn(args...)
+	vm.sp = vm.sp - numArgs - 1 // drop the arguments and the builtin itself
+
+	if errObj, ok := result.(*object.Error); ok {
+		if errObj.Thrown {
+			return errObj
+		}
+		if !errObj.Pos.IsValid() {
+			// error values remember where they were created
+			frame := vm.currentFrame()
+			errObj.Pos = frame.cl.Fn.SourceMap.Lookup(frame.ip)
+		}
+	}
+
+	if result != nil {
+		return vm.push(result)
+	}
+	return vm.push(Null)
+}
+
+// Wraps the CompiledFunction constant into a Closure capturing the numFree
+// values on top of the stack.
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	globals := vm.currentFrame().cl.Globals
+	constant := globals.Constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]*object.Cell, numFree)
+	for i := 0; i < numFree; i++ {
+		switch captured := vm.stack[vm.sp-numFree+i].(type) {
+		case *object.Cell:
+			free[i] = captured
+		default:
+			// the enclosing function, pushed by OpCurrentClosure
+			free[i] = object.NewClosedCell(captured)
+		}
+	}
+	vm.sp = vm.sp - numFree
+
+	closure := &object.Closure{Fn: function, Free: free, Globals: globals}
+	return vm.push(closure)
 }
@@ -2,11 +2,15 @@ package vm
 
 import (
 	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/tjapit/monkey/src/ast"
 	"github.com/tjapit/monkey/src/compiler"
 	"github.com/tjapit/monkey/src/lexer"
+	"github.com/tjapit/monkey/src/module"
 	"github.com/tjapit/monkey/src/object"
 	"github.com/tjapit/monkey/src/parser"
 )
@@ -44,6 +48,49 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+func testBigIntObject(expected *big.Int, actual object.Object) error {
+	result, ok := actual.(*object.BigInt)
+	if !ok {
+		return fmt.Errorf("object is not BigInt. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value.Cmp(expected) != 0 {
+		return fmt.Errorf(
+			"object has wrong value. want=%s, got=%s",
+			expected,
+			result.Value,
+		)
+	}
+
+	return nil
+}
+
+// Parses a decimal big integer for expected test values.
+func bigInt(s string) *big.Int {
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid big integer " + s)
+	}
+	return value
+}
+
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf(
+			"object has wrong value. want=%g, got=%g",
+			expected,
+			result.Value,
+		)
+	}
+
+	return nil
+}
+
 func testBooleanObject(expected bool, actual object.Object) error {
 	result, ok := actual.(*object.Boolean)
 	if !ok {
@@ -61,6 +108,26 @@ func testBooleanObject(expected bool, actual object.Object) error {
 	return nil
 }
 
+func testStringObject(expected string, actual object.Object) error {
+	result, ok := actual.(*object.String)
+	if !ok {
+		return fmt.Errorf("object is not String. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf(
+			"object has wrong value. want=%q, got=%q",
+			expected,
+			result.Value,
+		)
+	}
+
+	return nil
+}
+
+// Expected quote, given by the String() of its node.
+type quote string
+
 func testExpectedObject(
 	t *testing.T,
 	expected interface{},
@@ -75,16 +142,117 @@ func testExpectedObject(
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
 
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
+
+	case *big.Int:
+		err := testBigIntObject(expected, actual)
+		if err != nil {
+			t.Errorf("testBigIntObject failed: %s", err)
+		}
+
+	case quote:
+		q, ok := actual.(*object.Quote)
+		if !ok || q.Node.String() != string(expected) {
+			t.Errorf("object is not Quote %q. got=%T (%+v)", expected, actual, actual)
+		}
+
 	case bool:
 		err := testBooleanObject(bool(expected), actual)
 		if err != nil {
 			t.Errorf("testBooleanObject failed: %s", err)
 		}
+
+	case string:
+		err := testStringObject(expected, actual)
+		if err != nil {
+			t.Errorf("testStringObject failed: %s", err)
+		}
+
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Errorf("object not Array: %T (%+v)", actual, actual)
+			return
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf(
+				"wrong num of elements. want=%d, got=%d",
+				len(expected),
+				len(array.Elements),
+			)
+			return
+		}
+
+		for i, expectedElem := range expected {
+			err := testIntegerObject(int64(expectedElem), array.Elements[i])
+			if err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+
+	case map[object.HashKey]int64:
+		hash, ok := actual.(*object.Hash)
+		if !ok {
+			t.Errorf("object is not Hash. got=%T (%+v)", actual, actual)
+			return
+		}
+
+		if len(hash.Pairs) != len(expected) {
+			t.Errorf(
+				"hash has wrong number of Pairs. want=%d, got=%d",
+				len(expected),
+				len(hash.Pairs),
+			)
+			return
+		}
+
+		for expectedKey, expectedValue := range expected {
+			pair, ok := hash.Pairs[expectedKey]
+			if !ok {
+				t.Errorf("no pair for given key in Pairs")
+			}
+
+			err := testIntegerObject(expectedValue, pair.Value)
+			if err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+
+	case *object.Null:
+		if actual != Null {
+			t.Errorf("object is not Null: %T (%+v)", actual, actual)
+		}
+
+	case *object.Error:
+		errObj, ok := actual.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error: %T (%+v)", actual, actual)
+			return
+		}
+
+		if errObj.Message != expected.Message {
+			t.Errorf(
+				"wrong error message. want=%q, got=%q",
+				expected.Message,
+				errObj.Message,
+			)
+		}
 	}
 }
 
 func runVmTests(t *testing.T, testCases []vmTestCase) {
 	t.Helper()
+	runVmTestsWithSetup(t, testCases, func(*VM) {})
+}
+
+// Runs the test cases on VMs prepared by setup before they run.
+func runVmTestsWithSetup(t *testing.T, testCases []vmTestCase, setup func(*VM)) {
+	t.Helper()
 
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -97,7 +265,16 @@ func runVmTests(t *testing.T, testCases []vmTestCase) {
 			}
 
 			vm := New(comp.Bytecode())
+			setup(vm)
 			err = vm.Run()
+
+			// uncaught errors abort the run, compare them as the result
+			if errObj, ok := err.(*object.Error); ok {
+				if _, ok := tC.expected.(*object.Error); ok {
+					testExpectedObject(t, tC.expected, errObj)
+					return
+				}
+			}
 			if err != nil {
 				t.Fatalf("vm error: %s", err)
 			}
@@ -109,6 +286,38 @@ func runVmTests(t *testing.T, testCases []vmTestCase) {
 	}
 }
 
+func TestUnsetBindings(t *testing.T) {
+	testCases := []vmTestCase{
+		{
+			"Test 1",
+			`try { let a = fn() { throw "e" }(); } catch (e) {}; puts(a);`,
+			&object.Error{Message: "identifier not found: a"},
+		},
+		{
+			"Test 2",
+			"if (false) { let z = 1; }; puts(z);",
+			&object.Error{Message: "identifier not found: z"},
+		},
+		{
+			"Test 3",
+			"fn() { if (false) { let z = 1; }; z }()",
+			&object.Error{Message: "identifier not found: z"},
+		},
+		{
+			"Test 4",
+			"let f = fn(x) { if (x) { let y = 1; }; y }; f(true); f(false)",
+			&object.Error{Message: "identifier not found: y"},
+		},
+		{
+			"Test 5",
+			"fn() { if (false) { let z = 1; }; fn() { z }() }()",
+			&object.Error{Message: "identifier not found: z"},
+		},
+	}
+
+	runVmTests(t, testCases)
+}
+
 func TestIntegerArithmetic(t *testing.T) {
 	testCases := []vmTestCase{
 		{"Test 1", "1", 1},
@@ -122,6 +331,32 @@ func TestIntegerArithmetic(t *testing.T) {
 		{"Test 9", "-5", -5},
 		{"Test 10", "-50 + 100 + -50", 0},
 		{"Test 11", "(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"Test 12", "5 % 2", 1},
+		{"Test 13", "10 % 5", 0},
+		{"Test 14", "-7 % 3", -1},
+		{"Test 15", "2 + 7 % 4 * 2", 8},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "3.14", 3.14},
+		{"Test 2", "-2.5", -2.5},
+		{"Test 3", "1.5 + 1.5", 3.0},
+		{"Test 4", "1 + 0.5", 1.5},
+		{"Test 5", "0.5 * 4", 2.0},
+		{"Test 6", "7 / 2.0", 3.5},
+		{"Test 7", "1e3 - 1", 999.0},
+		{"Test 8", "1.5 < 2.5", true},
+		{"Test 9", "1.5 > 2", false},
+		{"Test 10", "1 == 1.0", true},
+		{"Test 11", "1.0 != 1", false},
+		{"Test 12", "2 > 1.99", true},
+		{"Test 13", "{0.5: 5}[0.5]", 5},
+		{"Test 14", "{1: 5}[1.0]", 5},
+		{"Test 15", "5.5 % 2", 1.5},
 	}
 
 	runVmTests(t, testCases)
@@ -154,7 +389,839 @@ func TestBooleanExpressions(t *testing.T) {
 		{"Test 21", "!!true", true},
 		{"Test 22", "!!false", false},
 		{"Test 23", "!!5", true},
+		{"Test 24", "1 <= 1", true},
+		{"Test 25", "2 <= 1", false},
+		{"Test 26", "1 >= 1", true},
+		{"Test 27", "1 >= 2", false},
+		{"Test 28", "1.5 <= 2", true},
+		{"Test 29", "2 >= 2.5", false},
+		{"Test 30", "true && true", true},
+		{"Test 31", "true && false", false},
+		{"Test 32", "false || true", true},
+		{"Test 33", "false || false", false},
+		{"Test 34", "1 < 2 && 2 < 3", true},
+		{"Test 35", "1 > 2 || 2 > 3", false},
+		{"Test 36", "false && len(1)", false},
+		{"Test 37", "true || len(1)", true},
+		{"Test 38", "1 && \"monkey\"", true},
+		{"Test 39", "if (1 > 2 || true) { 10 } else { 20 } == 10", true},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestConditionals(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "if (true) { 10 } else { 20 }", 10},
+		{"Test 2", "if (false) { 10 } else { 20 }", 20},
+		{"Test 3", "if (1) { 10 } else { 20 }", 10},
+		{"Test 4", "if (1 < 2) { 10 } else { 20 }", 10},
+		{"Test 5", "if (1 > 2) { 10 } else { 20 }", 20},
+		{"Test 6", "if (1 > 2) { 10 }", Null},
+		{"Test 7", "if (false) { 10 }", Null},
+		{"Test 8", "if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		{"Test 9", "!(if (false) { 5; })", true},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "let one = 1; one", 1},
+		{"Test 2", "let one = 1; let two = 2; one + two", 3},
+		{"Test 3", "let one = 1; let two = one + one; one + two", 3},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestStringExpressions(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", `"monkey"`, "monkey"},
+		{"Test 2", `"mon" + "key"`, "monkey"},
+		{"Test 3", `"mon" + "key" + "banana"`, "monkeybanana"},
+		{"Test 4", `"monkey" == "monkey"`, true},
+		{"Test 5", `"monkey" != "monkey"`, false},
+		{"Test 6", `"monkey" == "banana"`, false},
+		{"Test 7", `let a = "mon"; a + "key" == "monkey"`, true},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestArrayLiterals(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "[]", []int{}},
+		{"Test 2", "[1, 2, 3]", []int{1, 2, 3}},
+		{"Test 3", "[1 + 2, 3 * 4, 5 + 6]", []int{3, 12, 11}},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestHashLiterals(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "{}", map[object.HashKey]int64{}},
+		{
+			"Test 2",
+			"{1: 2, 2: 3}",
+			map[object.HashKey]int64{
+				(&object.Integer{Value: 1}).HashKey(): 2,
+				(&object.Integer{Value: 2}).HashKey(): 3,
+			},
+		},
+		{
+			"Test 3",
+			"{1 + 1: 2 * 2, 3 + 3: 4 * 4}",
+			map[object.HashKey]int64{
+				(&object.Integer{Value: 2}).HashKey(): 4,
+				(&object.Integer{Value: 6}).HashKey(): 16,
+			},
+		},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "[1, 2, 3][1]", 2},
+		{"Test 2", "[1, 2, 3][0 + 2]", 3},
+		{"Test 3", "[[1, 1, 1]][0][0]", 1},
+		{"Test 4", "[][0]", Null},
+		{"Test 5", "[1, 2, 3][99]", Null},
+		{"Test 6", "[1][-1]", Null},
+		{"Test 7", "{1: 1, 2: 2}[1]", 1},
+		{"Test 8", "{1: 1, 2: 2}[2]", 2},
+		{"Test 9", "{1: 1}[0]", Null},
+		{"Test 10", "{}[0]", Null},
+		{"Test 11", `"monkey"[0]`, "m"},
+		{"Test 12", `"héllo"[1]`, "é"},
+		{"Test 13", `"🐒!"[1]`, "!"},
+		{"Test 14", `"abc"[3]`, Null},
+		{"Test 15", `"abc"[-1]`, Null},
+		{"Test 16", "{100000000000000000000: 5}[99999999999999999999 + 1]", 5},
+		{"Test 17", "{1: 5}[100000000000000000000 - 99999999999999999999]", 5},
+		{"Test 18", "{1e20: 5}[100000000000000000000]", 5},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestIndexErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "999[1]", "index operator not supported: INTEGER"},
+		{"Test 2", `{"name": "Monkey"}[[]]`, "unusable as hash key: ARRAY"},
+		{"Test 3", "{[]: 1}", "unusable as hash key: ARRAY"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			program := parse(tC.input)
+
+			comp := compiler.New()
+			err := comp.Compile(program)
+			if err != nil {
+				t.Fatalf("compiler error: %s", err)
+			}
+
+			vm := New(comp.Bytecode())
+			err = vm.Run()
+			if err == nil {
+				t.Fatalf("expected VM error but resulted in none.")
+			}
+
+			if err.Error() != tC.expected {
+				t.Fatalf("wrong VM error: want=%q, got=%q", tC.expected, err)
+			}
+		})
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "let a = 5; a = 10; a;", 10},
+		{"Test 2", "let a = 5; a = a + 1;", 6},
+		{"Test 3", "let a = 5; a += 2; a;", 7},
+		{"Test 4", "let a = 5; a -= 2; a;", 3},
+		{"Test 5", "let a = 5; a *= 2; a;", 10},
+		{"Test 6", "let a = 5; a /= 2; a;", 2},
+		{"Test 7", "let a = 5; a %= 2; a;", 1},
+		{"Test 8", "let a = 1; let b = 2; a = b = 3; a + b;", 6},
+		{"Test 9", "let a = 1; let f = fn() { a = 5; }; f(); a;", 5},
+		{"Test 10", "let a = 1; let f = fn(a) { a = 5; }; f(2); a;", 1},
+		{"Test 11", "let f = fn() { let n = 1; n += 1; n }; f();", 2},
+		{"Test 12", "let arr = [1, 2, 3]; arr[1] = 5; arr[1];", 5},
+		{"Test 13", "let arr = [1, 2, 3]; arr[2] += 5; arr[2];", 8},
+		{"Test 14", `let h = {"a": 1}; h["b"] = 2; h["a"] + h["b"];`, 3},
+		{"Test 15", `let h = {"a": 1}; h["a"] *= 10; h["a"];`, 10},
+		{"Test 16", "let arr = [[1], [2]]; arr[1][0] = 7; arr[1][0];", 7},
+		{"Test 17", "let i = 0; let next = fn() { i += 1; i }; let arr = [0, 0]; arr[next()] += 3; arr[1] + i;", 4},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestDivisionByZero(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "1 / 0", &object.Error{Message: "division by zero"}},
+		{"Test 2", "let x = 5; x %= 0;", &object.Error{Message: "modulo by zero"}},
+		{"Test 3", `try { 1 / 0 } catch (e) { e["message"] }`, "division by zero"},
+		{"Test 4", "1.0 / 0 > 1e308", true},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "9223372036854775807 + 1", &object.Error{Message: "integer overflow: 9223372036854775807 + 1"}},
+		{"Test 2", "-9223372036854775807 - 2", &object.Error{Message: "integer overflow: -9223372036854775807 - 2"}},
+		{"Test 3", "4294967296 * 4294967296", &object.Error{Message: "integer overflow: 4294967296 * 4294967296"}},
+		{"Test 4", "let min = -9223372036854775807 - 1; min / -1", &object.Error{Message: "integer overflow: -9223372036854775808 / -1"}},
+		{"Test 5", "let min = -9223372036854775807 - 1; -min", &object.Error{Message: "integer overflow: -(-9223372036854775808)"}},
+		{"Test 6", "9223372036854775806 + 1", 9223372036854775807},
+		{"Test 7", "-4294967296 * 2147483648", -9223372036854775808},
+		{"Test 8", `try { 9223372036854775807 * 2 } catch (e) { e["kind"] }`, "RuntimeError"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			program := parse(tC.input)
+
+			comp := compiler.New()
+			err := comp.Compile(program)
+			if err != nil {
+				t.Fatalf("compiler error: %s", err)
+			}
+
+			vm := New(comp.Bytecode())
+			vm.CheckedArithmetic = true
+			err = vm.Run()
+
+			if errObj, ok := err.(*object.Error); ok {
+				testExpectedObject(t, tC.expected, errObj)
+				return
+			}
+			if err != nil {
+				t.Fatalf("vm error: %s", err)
+			}
+
+			testExpectedObject(t, tC.expected, vm.LastPopped())
+		})
+	}
+}
+
+func TestBigIntArithmetic(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "9223372036854775807 + 1", bigInt("9223372036854775808")},
+		{"Test 2", "let min = -9223372036854775807 - 1; min - 1", bigInt("-9223372036854775809")},
+		{"Test 3", "4294967296 * 4294967296", bigInt("18446744073709551616")},
+		{"Test 4", "let min = -9223372036854775807 - 1; min / -1", bigInt("9223372036854775808")},
+		{"Test 5", "let min = -9223372036854775807 - 1; -min", bigInt("9223372036854775808")},
+		{"Test 6", "let min = -9223372036854775807 - 1; min % -1", 0},
+		{"Test 7", "100000000000000000000", bigInt("100000000000000000000")},
+		{"Test 8", "100000000000000000000 - 99999999999999999999", 1},
+		{"Test 9", "-9223372036854775808", -9223372036854775808},
+		{"Test 10", "18446744073709551616 / 4294967296", 4294967296},
+		{"Test 11", "-100000000000000000007 % 10", -7},
+		{"Test 12", "-100000000000000000007 / 10", bigInt("-10000000000000000000")},
+		{"Test 13", "100000000000000000000 > 9223372036854775807", true},
+		{"Test 14", "9223372036854775807 + 1 == 9223372036854775808", true},
+		{"Test 15", "100000000000000000000 != 100000000000000000000", false},
+		{"Test 16", "100000000000000000000 / 0", &object.Error{Message: "division by zero"}},
+		{"Test 17", "100000000000000000000 * 0.5", 5e19},
+		{"Test 18", "let x = 9223372036854775807; x += 1; x -= 1; x", 9223372036854775807},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestAssignErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "let arr = [1]; arr[1] = 5;", "index out of range: 1"},
+		{"Test 2", `let s = "abc"; s[0] = "x";`, "index assignment not supported: STRING"},
+		{"Test 3", "let h = {}; h[[]] = 1;", "unusable as hash key: ARRAY"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			program := parse(tC.input)
+
+			comp := compiler.New()
+			err := comp.Compile(program)
+			if err != nil {
+				t.Fatalf("compiler error: %s", err)
+			}
+
+			vm := New(comp.Bytecode())
+			err = vm.Run()
+			if err == nil {
+				t.Fatalf("expected VM error but resulted in none.")
+			}
+
+			if err.Error() != tC.expected {
+				t.Fatalf("wrong VM error: want=%q, got=%q", tC.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoops(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "let i = 0; while (i < 5) { i += 1; } i;", 5},
+		{"Test 2", "let i = 0; while (true) { i += 1; if (i == 3) { break; } } i;", 3},
+		{"Test 3", "let i = 0; let s = 0; while (i < 5) { i += 1; if (i % 2 == 0) { continue; } s += i; } s;", 9},
+		{"Test 4", "let s = 0; for (x in [1, 2, 3]) { s += x; } s;", 6},
+		{"Test 5", "let s = 0; for (i, x in [10, 20]) { s += i * x; } s;", 20},
+		{"Test 6", `let s = ""; for (c in "abc") { s = c + s; } s;`, "cba"},
+		{"Test 7", `let s = 0; for (i, c in "héllo") { s += i; } s;`, 10},
+		{"Test 8", `let s = ""; for (k in {"b": 1, "a": 2}) { s += k; } s;`, "ab"},
+		{"Test 9", `let s = 0; for (k, v in {"b": 1, "a": 2}) { s += v; } s;`, 3},
+		{"Test 10", "let s = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { break; } s += x; } s;", 3},
+		{"Test 11", "let s = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { continue; } s += x; } s;", 7},
+		{"Test 12", "let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } 0 }; f();", 20},
+		{"Test 13", "let s = 0; for (x in [1, 2]) { for (y in [1, 2, 3]) { if (y == 2) { break; } s += x * y; } } s;", 3},
+		{"Test 14", "let i = 0; while (i < 100000) { i += 1; } i;", 100000},
+		{"Test 15", "let f = fn(n) { let s = 0; while (n > 0) { s += n; n -= 1; } s }; f(4);", 10},
+		{"Test 16", "let f = fn(arr) { let s = 0; for (x in arr) { s += x; } s }; f([1, 2]) + f([3]);", 6},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestTryExpressions(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "try { 1 } catch (e) { 2 }", 1},
+		{"Test 2", `try { throw "bad"; 1 } catch (e) { e["message"] }`, "bad"},
+		{"Test 3", `try { throw "bad"; } catch (e) { e["kind"] }`, "Error"},
+		{"Test 4", `try { 1 + true } catch (e) { e["kind"] }`, "RuntimeError"},
+		{"Test 5", `try { throw error("nope", "ValueError"); } catch (e) { e["kind"] + ": " + e["message"] }`, "ValueError: nope"},
+		{"Test 6", `try { throw 42; } catch (e) { e["message"] }`, "42"},
+		{"Test 7", `let f = fn(x) { if (x > 1) { throw "big"; } x }; try { f(1) + f(2) } catch (e) { -1 }`, -1},
+		{"Test 8", "let s = 0; try { s += 1; } finally { s += 10; } s;", 11},
+		{"Test 9", `let s = 0; try { throw "x"; } catch (e) { s += 1; } finally { s += 10; } s;`, 11},
+		{"Test 10", "let s = 0; let f = fn() { try { return 1; } finally { s = 5; } }; f() + s;", 6},
+		{"Test 11", `let s = 0; try { try { throw "in"; } finally { s = 1; } } catch (e) { s }`, 1},
+		{"Test 12", `try { try { throw "a"; } catch (e) { throw e["message"] + "b"; } } catch (e) { e["message"] }`, "ab"},
+		{"Test 13", "let i = 0; let s = 0; while (i < 5) { i += 1; try { if (i == 2) { continue; } if (i == 4) { break; } s += i; } finally { s += 10; } } s;", 44},
+		{"Test 14", `try { throw "x"; } catch (e) { }`, Null},
+		{"Test 15", "try { 5 } finally { 6 }", 5},
+		{"Test 16", `let e = error("boom"); e["message"]`, "boom"},
+		{"Test 17", `let e = error("boom"); e["missing"]`, Null},
+		{"Test 18", `let r = try { throw "x"; } catch (e) { e }; r["message"]`, "x"},
+		{"Test 19", `let f = fn() { try { throw "x"; } catch (e) { return 1; } finally { 2 } }; f()`, 1},
+		{"Test 20", `throw "oops"; 5`, &object.Error{Message: "oops"}},
+		{"Test 21", `try { throw "a"; } finally { 1 }`, &object.Error{Message: "a"}},
+		{"Test 22", `try { 1 } finally { throw "f"; }`, &object.Error{Message: "f"}},
+		{"Test 23", `let f = fn() { throw "deep"; }; let g = fn() { f() + 1 }; g()`, &object.Error{Message: "deep"}},
+		{"Test 24", "error(1)", &object.Error{Message: "first argument to `error` must be STRING, got =INTEGER"}},
+		{"Test 25", `error("a", "b", "c")`, &object.Error{Message: "wrong number of arguments. want=1 or 2, got =3"}},
+		{"Test 26", "let r = try {\n  [1][\"a\"]\n} catch (e) { e };\nr[\"position\"];", "2:6"},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestLoopErrors(t *testing.T) {
+	program := parse("for (x in 5) { x; }")
+
+	comp := compiler.New()
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	err = vm.Run()
+	if err == nil {
+		t.Fatalf("expected VM error but resulted in none.")
+	}
+
+	expected := "cannot iterate over INTEGER"
+	if err.Error() != expected {
+		t.Fatalf("wrong VM error: want=%q, got=%q", expected, err)
+	}
+}
+
+func TestCallingFunctions(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "let fivePlusTen = fn() { 5 + 10; }; fivePlusTen();", 15},
+		{"Test 2", "let one = fn() { 1; }; let two = fn() { 2; }; one() + two()", 3},
+		{"Test 3", "let earlyExit = fn() { return 99; 100; }; earlyExit();", 99},
+		{"Test 4", "let noReturn = fn() { }; noReturn();", Null},
+		{"Test 5", "let returnsOne = fn() { 1; }; let returnsOneReturner = fn() { returnsOne; }; returnsOneReturner()();", 1},
+		{"Test 6", "let one = fn() { let one = 1; one }; one();", 1},
+		{"Test 7", "let identity = fn(a) { a; }; identity(4);", 4},
+		{"Test 8", "let sum = fn(a, b) { let c = a + b; c; }; sum(1, 2) + sum(3, 4);", 10},
+		{"Test 9", "let globalNum = 10; let sum = fn(a, b) { let c = a + b; c + globalNum; }; let outer = fn() { sum(1, 2) + sum(3, 4) + globalNum; }; outer() + globalNum;", 50},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestClosures(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "let newClosure = fn(a) { fn() { a; }; }; let closure = newClosure(99); closure();", 99},
+		{"Test 2", "let newAdder = fn(x) { fn(y) { x + y } }; let addTwo = newAdder(2); addTwo(3);", 5},
+		{"Test 3", "let newAdder = fn(a, b) { let c = a + b; fn(d) { c + d } }; newAdder(1, 2)(8);", 11},
+		{"Test 4", "let newAdder = fn(a) { fn(b) { fn(c) { a + b + c } } }; newAdder(1)(2)(3);", 6},
+		{"Test 5", "let c = 0; let inc = fn() { c = c + 1 }; inc(); inc(); c", 2},
+		{
+			"Test 6",
+			"let counter = fn() { let c = 0; fn() { c = c + 1 } }; let inc = counter(); inc(); inc(); inc()",
+			3,
+		},
+		{
+			"Test 7",
+			"let pair = fn() { let n = 0; [fn() { n += 1 }, fn() { n }] }; let p = pair(); p[0](); p[0](); p[1]()",
+			2,
+		},
+		{"Test 8", "fn() { let x = 1; let set = fn() { x = 5 }; set(); x }()", 5},
+		{"Test 9", "fn() { let x = 1; let get = fn() { x }; x = 7; get() }()", 7},
+		{"Test 10", "fn() { let x = 1; fn() { fn() { x = 3 } }()(); x }()", 3},
+		{
+			"Test 11",
+			"let counter = fn() { let c = 0; fn() { c += 1 } }; let a = counter(); let b = counter(); a(); a(); b()",
+			1,
+		},
+		{"Test 12", "let f = fn() { f = 5; }; f(); f", 5},
+		{"Test 13", "fn() { let f = fn() { f = 5; }; f(); f }()", 5},
+		{
+			"Test 14",
+			`fn() {
+				let x = 1;
+				let get = fn() { x };
+				try { fn() { let y = 2; throw "e"; }() } catch (e) {};
+				x = 4;
+				get()
+			}()`,
+			4,
+		},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	testCases := []vmTestCase{
+		{
+			"Test 1",
+			"let countDown = fn(x) { if (x == 0) { return 0; } else { countDown(x - 1); } }; countDown(1);",
+			0,
+		},
+		{
+			"Test 2",
+			"let wrapper = fn() { let countDown = fn(x) { if (x == 0) { return 0; } else { countDown(x - 1); } }; countDown(1); }; wrapper();",
+			0,
+		},
+		{
+			"Test 3",
+			`
+      let fibonacci = fn(x) {
+        if (x == 0) {
+          return 0;
+        } else {
+          if (x == 1) {
+            return 1;
+          } else {
+            fibonacci(x - 1) + fibonacci(x - 2);
+          }
+        }
+      };
+      fibonacci(15);
+      `,
+			610,
+		},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "let f = fn(a, b = 10) { a + b }; f(1)", 11},
+		{"Test 2", "let f = fn(a, b = 10) { a + b }; f(1, 2)", 3},
+		{"Test 3", "let f = fn(a, b = a * 2, c = a + b) { a * 100 + b * 10 + c }; f(1)", 123},
+		{"Test 4", "let f = fn(a, b = a * 2, c = a + b) { a * 100 + b * 10 + c }; f(1, 5)", 156},
+		{"Test 5", "let f = fn(...xs) { len(xs) }; f()", 0},
+		{"Test 6", "let f = fn(...xs) { len(xs) }; f(1, 2, 3)", 3},
+		{"Test 7", "let f = fn(a, ...rest) { a + len(rest) * 10 + rest[1] }; f(1, 2, 3)", 24},
+		{"Test 8", "let add = fn(a, b, c) { a * 100 + b * 10 + c }; add(...[1, 2, 3])", 123},
+		{"Test 9", "let add = fn(a, b, c) { a * 100 + b * 10 + c }; add(1, ...[2], 3)", 123},
+		{"Test 10", "let add = fn(a, b, c) { a * 100 + b * 10 + c }; add(...[1], ...[2, 3])", 123},
+		{
+			"Test 11",
+			"let sum = fn(...xs) { let t = 0; for (x in xs) { t += x; } t }; sum(...[1, 2], 3, ...[], ...[4])",
+			10,
+		},
+		{"Test 12", `len(...["four"])`, 4},
+		{"Test 13", "let n = 5; let f = fn(a = n) { fn(b = a) { a + b } }; f()()", 10},
+		{"Test 14", "let f = fn(a, b = 1) { a + b }; let args = [1, 2]; f(...args) + f(...[4])", 8},
+		{
+			"Test 15",
+			"let fact = fn(n, acc = 1) { if (n == 0) { acc } else { fact(n - 1, acc * n) } }; fact(5)",
+			120,
+		},
 	}
 
 	runVmTests(t, testCases)
 }
+
+func TestCallingFunctionsErrors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "fn() { 1; }(1);", "wrong number of arguments. want=0, got =1"},
+		{"Test 2", "fn(a) { a; }();", "wrong number of arguments. want=1, got =0"},
+		{"Test 3", "fn(a, b) { a + b; }(1);", "wrong number of arguments. want=2, got =1"},
+		{"Test 4", "1();", "calling non-function and non-built-in"},
+		{"Test 5", "fn(a, b = 1) { a }()", "wrong number of arguments. want=1 to 2, got =0"},
+		{"Test 6", "fn(a, b = 1) { a }(1, 2, 3)", "wrong number of arguments. want=1 to 2, got =3"},
+		{"Test 7", "fn(a, ...r) { a }()", "wrong number of arguments. want at least 1, got =0"},
+		{"Test 8", "fn(a) { a }(1, 2)", "wrong number of arguments. want=1, got =2"},
+		{"Test 9", "len(...1)", "cannot spread INTEGER into arguments"},
+		{"Test 10", "fn(a) { a }(...[1, 2])", "wrong number of arguments. want=1, got =2"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			program := parse(tC.input)
+
+			comp := compiler.New()
+			err := comp.Compile(program)
+			if err != nil {
+				t.Fatalf("compiler error: %s", err)
+			}
+
+			vm := New(comp.Bytecode())
+			err = vm.Run()
+			if err == nil {
+				t.Fatalf("expected VM error but resulted in none.")
+			}
+
+			if err.Error() != tC.expected {
+				t.Fatalf("wrong VM error: want=%q, got=%q", tC.expected, err)
+			}
+		})
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", `len("")`, 0},
+		{"Test 2", `len("four")`, 4},
+		{"Test 3", `len("hello world")`, 11},
+		{
+			"Test 4",
+			`len(1)`,
+			&object.Error{Message: "argument to `len` not supported, got =INTEGER"},
+		},
+		{
+			"Test 5",
+			`len("one", "two")`,
+			&object.Error{Message: "wrong number of arguments. want=1, got =2"},
+		},
+		{"Test 6", `len([1, 2, 3])`, 3},
+		{"Test 7", `len([])`, 0},
+		{"Test 8", `puts("hello", "world!")`, Null},
+		{"Test 9", `first([1, 2, 3])`, 1},
+		{"Test 10", `first([])`, Null},
+		{
+			"Test 11",
+			`first(1)`,
+			&object.Error{Message: "argument to `first` must be ARRAY, got =INTEGER"},
+		},
+		{"Test 12", `last([1, 2, 3])`, 3},
+		{"Test 13", `last([])`, Null},
+		{"Test 14", `rest([1, 2, 3])`, []int{2, 3}},
+		{"Test 15", `rest([])`, Null},
+		{"Test 16", `push([], 1)`, []int{1}},
+		{
+			"Test 17",
+			`push(1, 1)`,
+			&object.Error{Message: "first argument to `push` must be ARRAY, got =INTEGER"},
+		},
+		{"Test 18", `let f = fn() { len([1, 2]) }; f()`, 2},
+		{"Test 19", `len("héllo 🐒")`, 7},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestQuote(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", `quote(5 + 8)`, quote("(5 + 8)")},
+		{"Test 2", `quote(unquote(4 + 4) + 1)`, quote("(8 + 1)")},
+		{"Test 3", `let x = 2; quote(unquote(x) * unquote(x + 1))`, quote("(2 * 3)")},
+		{"Test 4", `quote(unquote(true == false))`, quote("false")},
+		{"Test 5", `quote(unquote(quote(4 + 4)))`, quote("(4 + 4)")},
+		{"Test 6", `quote(unquote(1.5) * unquote("s"))`, quote("(1.5 * s)")},
+		{"Test 7", `let f = fn(x) { quote(unquote(x) + 1) }; f(1); f(2)`, quote("(2 + 1)")},
+		{"Test 8", `quote(unquote([1]))`, &object.Error{Message: "cannot unquote ARRAY"}},
+		{"Test 9", `quote(unquote(...[1]))`, quote("unquote(...[1])")},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestDestructuring(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", "let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"Test 2", "let [a, ...rest] = [1, 2, 3]; len(rest) * 10 + rest[1]", 23},
+		{"Test 3", "let [a, ...rest] = [1]; len(rest)", 0},
+		{"Test 4", "let [...all] = [4, 5]; all[0] + all[1]", 9},
+		{"Test 5", `let {name, age} = {"age": 30, "name": "Ann", "x": 1}; len(name) + age`, 33},
+		{"Test 6", "let f = fn(pair) { let [p, q] = pair; p * q }; f([6, 7])", 42},
+		{"Test 7", "let g = fn(arr) { let [h, ...t] = arr; fn() { h + len(t) } }; g([10, 1, 1])()", 12},
+		{"Test 8", "let arr = [1, 2]; let [a, ...rest] = arr; rest[0] = 5; arr[1]", 2},
+		{"Test 9", "let [a, b] = [1, 2]; let [a, b] = [b, a]; a * 10 + b", 21},
+		{"Test 10", `let {x} = {"x": 1}; let [] = []; let {} = {}; x`, 1},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	testCases := []vmTestCase{
+		{
+			"Test 1",
+			"let [a, b] = [1];",
+			&object.Error{Message: "wrong number of elements to destructure. want=2, got =1"},
+		},
+		{
+			"Test 2",
+			"let [a] = [1, 2];",
+			&object.Error{Message: "wrong number of elements to destructure. want=1, got =2"},
+		},
+		{
+			"Test 3",
+			"let [a, b, ...c] = [1];",
+			&object.Error{Message: "too few elements to destructure. want at least 2, got =1"},
+		},
+		{
+			"Test 4",
+			"let [a] = 1;",
+			&object.Error{Message: "cannot destructure INTEGER as an array"},
+		},
+		{
+			"Test 5",
+			`let {a} = {"b": 1};`,
+			&object.Error{Message: `missing key "a" in destructured hash`},
+		},
+		{
+			"Test 6",
+			"let {a} = [1];",
+			&object.Error{Message: "cannot destructure ARRAY as a hash"},
+		},
+		{
+			"Test 7",
+			`let {a} = {1: 1};`,
+			&object.Error{Message: `missing key "a" in destructured hash`},
+		},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestMatch(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", `match (1) { 1 => "one", _ => "other" }`, "one"},
+		{"Test 2", `match (2) { 1 => "one", _ => "other" }`, "other"},
+		{"Test 3", `match (-1) { 1 => "one", -1 => "minus one" }`, "minus one"},
+		{"Test 4", `match (2.0) { 2 => "two" }`, "two"},
+		{"Test 5", `match ("hi") { "ho" => "no", "hi" => "yes" }`, "yes"},
+		{"Test 6", `match (false) { true => "t", false => "f" }`, "f"},
+		{"Test 7", `match ([1, 2]) { [] => "empty", [a] => "one", [a, b] => "two" }`, "two"},
+		{"Test 8", `match ([1, "x", 3]) { [1, s, ...rest] => s }`, "x"},
+		{"Test 9", `match ([[1, "in"]]) { [[2, s]] => "no", [[1, s]] => s }`, "in"},
+		{
+			"Test 10",
+			`match ({"kind": "circle", "r": "2"}) { {"kind": "rect"} => "rect", {"kind": "circle", r} => r }`,
+			"2",
+		},
+		{
+			"Test 11",
+			`match ({"name": "Ann", "age": 3}) { {name: "Bob"} => "bob", {name: n} => n }`,
+			"Ann",
+		},
+		{"Test 12", `match (5) { n if n < 3 => "small", n => "big" }`, "big"},
+		{"Test 13", `match ([3, 1]) { [a, b] if a < b => "asc", [a, b] => "desc" }`, "desc"},
+		{"Test 14", `let x = "outer"; match ("inner") { x => x }`, "inner"},
+		{"Test 15", `let f = fn(v) { match (v) { [h, ...t] => fn() { h } } }; f(["a", 1])()`, "a"},
+		{"Test 16", `match (1) { 1 => match ("a") { "b" => "x", _ => "y" }, _ => "z" }`, "y"},
+		{"Test 17", `match (1) { n if match (n) { 1 => false, _ => true } => "a", _ => "b" }`, "b"},
+		{"Test 18", `match (9223372036854775807 + 1) { 9223372036854775808 => "big" }`, "big"},
+		{"Test 19", `match (1) { {} => "hash", [] => "array", _ => "other" }`, "other"},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestMatchErrors(t *testing.T) {
+	testCases := []vmTestCase{
+		{"Test 1", `match (3) { 1 => "one" }`, &object.Error{Message: "no match arm for 3"}},
+		{
+			"Test 2",
+			`match ([1]) { [a] if a > 1 => a }`,
+			&object.Error{Message: "no match arm for [1]"},
+		},
+		{"Test 3", `match ("s") {}`, &object.Error{Message: "no match arm for s"}},
+	}
+
+	runVmTests(t, testCases)
+}
+
+func TestImport(t *testing.T) {
+	dir := writeTestModules(t)
+
+	testCases := []vmTestCase{
+		{"Test 1", `import("lib.mk")["square"](4)`, 16},
+		{"Test 2", `let m = import("lib.mk"); m["inc"](); m["inc"]()`, 2},
+		{"Test 3", `let a = import("lib.mk"); let b = import("lib.mk"); a["inc"](); b["inc"]()`, 2},
+		{"Test 4", `import("lib.mk")["base"]`, 10},
+		{"Test 5", `let f = fn() { import("base.mk")["value"] }; f() + f()`, 20},
+		{"Test 6", `let x = 1; import("base.mk"); x`, 1},
+		{
+			"Test 7",
+			`import("lib.mk")["count"]`,
+			&object.Error{Message: "module " + dir + "/lib.mk has no export count"},
+		},
+		{
+			"Test 8",
+			`import("missing.mk")`,
+			&object.Error{Message: `cannot import "missing.mk": module not found`},
+		},
+		{
+			"Test 9",
+			`import("cycle.mk")`,
+			&object.Error{
+				Message: "import cycle: " + dir + "/cycle.mk -> " + dir + "/cycle.mk",
+			},
+		},
+		{"Test 10", `import("fail.mk")`, &object.Error{Message: "division by zero"}},
+		{
+			"Test 11",
+			`try { import("fail.mk") } catch (e) { e["position"] }`,
+			dir + "/fail.mk:2:3",
+		},
+		{"Test 12", `let m = import("counter.mk"); m["inc"](); m["inc"](); m["count"]`, 2},
+	}
+
+	runVmTestsWithSetup(t, testCases, func(vm *VM) {
+		vm.Modules = module.NewLoader([]string{dir})
+	})
+}
+
+func TestGlobalsState(t *testing.T) {
+	symbolTable := compiler.NewSymbolTable()
+	constants := []object.Object{}
+	globals := make([]object.Object, GlobalsSize)
+
+	inputs := []string{"let a = 40;", "let b = a + 1;", "a + b + 1"}
+
+	var machine *VM
+	for _, input := range inputs {
+		comp := compiler.NewWithState(symbolTable, constants)
+		err := comp.Compile(parse(input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := comp.Bytecode()
+		constants = bytecode.Constants
+
+		machine = NewWithGlobalsState(bytecode, globals)
+		err = machine.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+	}
+
+	testExpectedObject(t, 82, machine.LastPopped())
+}
+
+func TestErrorPositions(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{"Test 1", "5 + true;", "1:3"},
+		{"Test 2", "let f = fn() {\n  -true\n};\nf();", "2:3"},
+		{"Test 3", `len(1)`, "1:4"},
+		{"Test 4", "let a = 1;\nthrow \"a\";", "2:1"},
+		{"Test 5", "let f = fn() {\n  throw error(\"f\");\n};\ntry { f() } catch (e) { throw e; }", "2:14"},
+		{"Test 6", "let x = 1;\nlet [a] = x;", "2:5"},
+		{"Test 7", "let f = fn() {\n  let {a} = {};\n};\nf();", "2:7"},
+		{"Test 8", "let x = 1;\nmatch (x) { 2 => 3 };", "2:1"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			program := parse(tC.input)
+
+			comp := compiler.New()
+			err := comp.Compile(program)
+			if err != nil {
+				t.Fatalf("compiler error: %s", err)
+			}
+
+			vm := New(comp.Bytecode())
+			err = vm.Run()
+
+			errObj, ok := err.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", err, err)
+			}
+
+			if errObj.Pos.String() != tC.expected {
+				t.Errorf(
+					"wrong error position. want=%s, got =%s",
+					tC.expected,
+					errObj.Pos,
+				)
+			}
+		})
+	}
+}
+
+// Modules imported by the import tests.
+var testModules = map[string]string{
+	"lib.mk": `
+let count = 0;
+export let square = fn(x) { x * x };
+export let inc = fn() { count += 1; count };
+export let base = import("base.mk")["value"];
+`,
+	"base.mk":    `export let value = 10;`,
+	"counter.mk": `export let count = 0; export let inc = fn() { count += 1; };`,
+	"cycle.mk":   `import("cycle.mk");`,
+	"fail.mk":    "let x = 1;\nx / 0;",
+	"bad.mk":     "let = 1;",
+}
+
+// Writes testModules to a temporary directory and returns it.
+func writeTestModules(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range testModules {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}